@@ -0,0 +1,209 @@
+package lib
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "image"
+    _ "image/gif"
+    _ "image/jpeg"
+    "image/png"
+    "io/ioutil"
+    "net/http"
+    "os"
+)
+
+const BLOB_DIR = "../../data/blobs"  // directory holding attachment contents, named by their hash
+const MAX_BLOB_SIZE = 5 << 20        // largest attachment in bytes
+const MAX_ATTACHMENTS = 4            // most attachments on a single post
+const THUMBNAIL_SIZE = 200           // longest side of an image thumbnail in pixels
+const MAX_IMAGE_SIDE = 8192          // longest side of an image attachment in pixels, checked before decoding
+
+// Content types accepted as attachments, as reported by http.DetectContentType
+var ALLOWED_TYPES = map[string]bool{
+    "image/png":                 true,
+    "image/jpeg":                true,
+    "image/gif":                 true,
+    "application/pdf":           true,
+    "text/plain; charset=utf-8": true,
+}
+
+var ErrBlobTooLarge = errors.New("attachment is too large")
+var ErrBlobType = errors.New("attachment type is not supported")
+var ErrImageSize = errors.New("image is too large")
+var ErrBlobHash = errors.New("blob does not match its hash")
+
+// Struct to hold a file attached to a post, the contents are stored in the blob store
+type Attachment struct {
+    Hash      string
+    Name      string
+    Type      string
+    Size      int
+    Thumbnail string  // hash of a PNG preview, empty if the attachment is not an image
+}
+
+// Struct used to copy a blob to a new replica
+type Blob struct {
+    Hash string
+    Data []byte
+}
+
+// Returns the hex encoded SHA-256 hash of the data, used as the blob's name
+func BlobHash(data []byte) string {
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}
+
+// Checks if a string is a well formed blob hash, so it can be used as a file name
+func validBlobHash(hash string) bool {
+    if len(hash) != sha256.Size * 2 {
+        return false
+    }
+    _, err := hex.DecodeString(hash)
+    return err == nil
+}
+
+// Stores the data in the blob store under its hash and returns the hash
+// Storing the same data again does nothing, blobs are never modified once written
+func StoreBlob(data []byte) (string, error) {
+    hash := BlobHash(data)
+    return hash, WriteBlob(hash, data)
+}
+
+// Stores data received under the given hash, fails if the data does not match the hash
+func WriteBlob(hash string, data []byte) error {
+    if !validBlobHash(hash) || BlobHash(data) != hash {
+        return ErrBlobHash
+    }
+    if BlobExists(hash) {
+        return nil
+    }
+    if err := os.MkdirAll(BLOB_DIR, os.ModePerm); err != nil {
+        return err
+    }
+    // write to a temporary file first so a partially written blob is never visible
+    tmp := BLOB_DIR + "/" + hash + ".tmp"
+    if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+        return err
+    }
+    return os.Rename(tmp, BLOB_DIR + "/" + hash)
+}
+
+// Returns the contents of the blob with the given hash
+func ReadBlob(hash string) ([]byte, error) {
+    if !validBlobHash(hash) {
+        return nil, os.ErrNotExist
+    }
+    return ioutil.ReadFile(BLOB_DIR + "/" + hash)
+}
+
+// Checks if the blob with the given hash is stored
+func BlobExists(hash string) bool {
+    if !validBlobHash(hash) {
+        return false
+    }
+    _, err := os.Stat(BLOB_DIR + "/" + hash)
+    return err == nil
+}
+
+// Returns the hashes of every stored blob
+func ListBlobs() []string {
+    files, err := ioutil.ReadDir(BLOB_DIR)
+    if err != nil {
+        return nil
+    }
+    var hashes []string
+    for _, file := range files {
+        if validBlobHash(file.Name()) {
+            hashes = append(hashes, file.Name())
+        }
+    }
+    return hashes
+}
+
+/*
+    Create attachment checks the size and type of an uploaded file and stores it in the blob store.
+    Images declaring a side longer than MAX_IMAGE_SIDE are rejected before their pixels are decoded,
+    since decoding them would need far more memory than the file size suggests.
+    Images also get a thumbnail stored as a separate blob. The thumbnail is generated the same way
+    on every server, so replicas creating the attachment end up with identical blobs.
+    Blobs are never removed with the posts using them, since other posts may share the same content.
+*/
+func CreateAttachment(name string, data []byte) (Attachment, error) {
+    if len(data) > MAX_BLOB_SIZE {
+        return Attachment{}, ErrBlobTooLarge
+    }
+    contentType := http.DetectContentType(data)
+    if !ALLOWED_TYPES[contentType] {
+        return Attachment{}, ErrBlobType
+    }
+    // a small file can declare huge dimensions, so read them from the header before decoding the pixels
+    config, _, configErr := image.DecodeConfig(bytes.NewReader(data))
+    if configErr == nil && (config.Width > MAX_IMAGE_SIDE || config.Height > MAX_IMAGE_SIDE) {
+        return Attachment{}, ErrImageSize
+    }
+    hash, err := StoreBlob(data)
+    if err != nil {
+        return Attachment{}, err
+    }
+    attachment := Attachment{Hash: hash, Name: name, Type: contentType, Size: len(data)}
+
+    if configErr != nil {
+        return attachment, nil  // not an image, or one that cannot be read, so there is no thumbnail
+    }
+    if img, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+        var thumb bytes.Buffer
+        if png.Encode(&thumb, thumbnail(img)) == nil {
+            attachment.Thumbnail, err = StoreBlob(thumb.Bytes())
+            if err != nil {
+                return Attachment{}, err
+            }
+        }
+    }
+    return attachment, nil
+}
+
+// Returns the user's posts that have the blob attached, either as the file itself or as its thumbnail
+func (user *UserInfo) PostsWithBlob(hash string) []Post {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    var posts []Post
+    for _, post := range user.Posts {
+        for _, attachment := range post.Attachments {
+            if attachment.Hash == hash || attachment.Thumbnail == hash {
+                posts = append(posts, post)
+                break
+            }
+        }
+    }
+    return posts
+}
+
+// Scales an image down with nearest neighbour sampling so its longest side is at most THUMBNAIL_SIZE
+func thumbnail(img image.Image) image.Image {
+    bounds := img.Bounds()
+    width, height := bounds.Dx(), bounds.Dy()
+    if width <= THUMBNAIL_SIZE && height <= THUMBNAIL_SIZE {
+        return img
+    }
+    newWidth, newHeight := THUMBNAIL_SIZE, THUMBNAIL_SIZE
+    if width > height {
+        newHeight = height * THUMBNAIL_SIZE / width
+    } else {
+        newWidth = width * THUMBNAIL_SIZE / height
+    }
+    if newWidth < 1 {
+        newWidth = 1
+    }
+    if newHeight < 1 {
+        newHeight = 1
+    }
+    thumb := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+    for y := 0; y < newHeight; y++ {
+        for x := 0; x < newWidth; x++ {
+            thumb.Set(x, y, img.At(bounds.Min.X + x * width / newWidth, bounds.Min.Y + y * height / newHeight))
+        }
+    }
+    return thumb
+}
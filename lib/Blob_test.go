@@ -0,0 +1,45 @@
+package lib
+
+import (
+    "bytes"
+    "encoding/binary"
+    "hash/crc32"
+    "testing"
+)
+
+// Builds a PNG whose header declares the given size, the pixel data is a few bytes whatever the size
+func pngHeader(width, height uint32) []byte {
+    var buf bytes.Buffer
+    buf.WriteString("\x89PNG\r\n\x1a\n")
+    chunk := func(kind string, data []byte) {
+        binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+        buf.WriteString(kind)
+        buf.Write(data)
+        binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(kind), data...)))
+    }
+    ihdr := make([]byte, 13)
+    binary.BigEndian.PutUint32(ihdr[0:], width)
+    binary.BigEndian.PutUint32(ihdr[4:], height)
+    ihdr[8] = 8  // bit depth
+    ihdr[9] = 2  // truecolour
+    chunk("IHDR", ihdr)
+    chunk("IDAT", []byte{0x78, 0x9c, 0x03, 0x00, 0x00, 0x00, 0x00, 0x01})
+    chunk("IEND", nil)
+    return buf.Bytes()
+}
+
+func TestCreateAttachmentRejectsHugeImages(t *testing.T) {
+    tests := []struct {
+        width, height uint32
+    }{
+        {MAX_IMAGE_SIDE + 1, 1},
+        {1, MAX_IMAGE_SIDE + 1},
+        {100000, 100000},
+    }
+    for _, test := range tests {
+        _, err := CreateAttachment("huge.png", pngHeader(test.width, test.height))
+        if err != ErrImageSize {
+            t.Errorf("CreateAttachment(%dx%d) error = %v, want %v", test.width, test.height, err, ErrImageSize)
+        }
+    }
+}
@@ -0,0 +1,89 @@
+package lib
+
+// Current UserInfo blocks the UserInfo passed in parameter
+// Any follow relationship, pending follow request or list membership between the two is removed in both directions
+func (user *UserInfo) Block(other *UserInfo) bool {
+    user.mut.Lock()
+    other.mut.Lock()
+    defer user.mut.Unlock()
+    defer other.mut.Unlock()
+    if user.Blocked[other.Username] {
+        return false
+    }
+    user.Blocked[other.Username] = true
+    delete(user.Following, other.Username)
+    delete(other.Following, user.Username)
+    user.FollowedBy = removeName(user.FollowedBy, other.Username)
+    other.FollowedBy = removeName(other.FollowedBy, user.Username)
+    user.FollowRequests = removeName(user.FollowRequests, other.Username)
+    other.FollowRequests = removeName(other.FollowRequests, user.Username)
+    for _, list := range user.Lists {
+        list.Members = removeName(list.Members, other.Username)
+    }
+    for _, list := range other.Lists {
+        list.Members = removeName(list.Members, user.Username)
+    }
+    return true
+}
+
+// Current UserInfo unblocks the given username, returns false if it was not blocked
+func (user *UserInfo) Unblock(name string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    if !user.Blocked[name] {
+        return false
+    }
+    delete(user.Blocked, name)
+    return true
+}
+
+// Checks if current UserInfo has blocked the given username
+func (user *UserInfo) HasBlocked(name string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return user.Blocked[name]
+}
+
+// Current UserInfo mutes the given username hiding their posts from its timeline, returns false if already muted
+func (user *UserInfo) Mute(name string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    if user.Muted[name] {
+        return false
+    }
+    user.Muted[name] = true
+    return true
+}
+
+// Current UserInfo unmutes the given username, returns false if it was not muted
+func (user *UserInfo) Unmute(name string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    if !user.Muted[name] {
+        return false
+    }
+    delete(user.Muted, name)
+    return true
+}
+
+// Checks if current UserInfo has muted the given username
+func (user *UserInfo) HasMuted(name string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return user.Muted[name]
+}
+
+// Checks if either user has blocked the other
+func EitherBlocked(user, other *UserInfo) bool {
+    return user.HasBlocked(other.Username) || other.HasBlocked(user.Username)
+}
+
+// Returns the slice without the given name
+func removeName(names []string, name string) []string {
+    for i := range names {
+        if names[i] == name {
+            return append(names[:i], names[i+1:]...)
+        }
+    }
+    return names
+}
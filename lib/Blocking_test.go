@@ -0,0 +1,59 @@
+package lib
+
+import (
+    "testing"
+)
+
+func TestBlockRemovesRelationships(t *testing.T) {
+    alice, bob, carol := NewUserInfo("alice", ""), NewUserInfo("bob", ""), NewUserInfo("carol", "")
+    alice.Follow(bob)
+    bob.Follow(alice)
+    carol.Follow(alice)
+    bob.SetPrivate(true)
+    bob.RequestFollow(alice)
+    alice.Lists["friends"] = &List{"friends", false, []string{"bob", "carol"}}
+    bob.Lists["people"] = &List{"people", false, []string{"alice"}}
+
+    if !alice.Block(bob) || alice.Block(bob) {
+        t.Errorf("alice should block bob exactly once")
+    }
+    if alice.IsFollowing(bob) || bob.IsFollowing(alice) || len(bob.GetFollowers()) != 0 {
+        t.Errorf("follows between alice and bob survived the block")
+    }
+    if len(alice.GetFollowRequests()) != 0 || len(bob.GetFollowRequests()) != 0 {
+        t.Errorf("follow requests between alice and bob survived the block")
+    }
+    if len(alice.Lists["friends"].Members) != 1 || len(bob.Lists["people"].Members) != 0 {
+        t.Errorf("list memberships survived the block: %q, %q", alice.Lists["friends"].Members, bob.Lists["people"].Members)
+    }
+    if !carol.IsFollowing(alice) || alice.GetFollowers()[0] != "carol" {
+        t.Errorf("the block changed carol's follow")
+    }
+
+    if !EitherBlocked(alice, bob) || !EitherBlocked(bob, alice) || EitherBlocked(alice, carol) {
+        t.Errorf("EitherBlocked does not match the block")
+    }
+    if alice.VisibleTo(bob) || !alice.VisibleTo(carol) {
+        t.Errorf("alice's posts should be hidden from bob only")
+    }
+
+    if !alice.Unblock("bob") || alice.Unblock("bob") || EitherBlocked(alice, bob) {
+        t.Errorf("alice should unblock bob exactly once")
+    }
+    if alice.IsFollowing(bob) {
+        t.Errorf("unblocking restored a follow")
+    }
+}
+
+func TestMute(t *testing.T) {
+    alice := NewUserInfo("alice", "")
+    if !alice.Mute("bob") || alice.Mute("bob") || !alice.HasMuted("bob") {
+        t.Errorf("alice should mute bob exactly once")
+    }
+    if alice.HasBlocked("bob") {
+        t.Errorf("muting blocked bob")
+    }
+    if !alice.Unmute("bob") || alice.Unmute("bob") || alice.HasMuted("bob") {
+        t.Errorf("alice should unmute bob exactly once")
+    }
+}
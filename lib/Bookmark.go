@@ -0,0 +1,154 @@
+package lib
+
+import (
+    "sort"
+    "strings"
+    "time"
+    "unicode/utf8"
+)
+
+const DEFAULT_COLLECTION = "Saved"      // collection bookmarks are added to when none is given
+const MAX_COLLECTIONS = 20              // most bookmark collections a user can have
+const MAX_COLLECTION_NAME_LENGTH = 25   // longest collection name in characters
+const MAX_BOOKMARKS = 1000              // most bookmarks in a single collection
+
+// Struct to hold a bookmark collection name and how many bookmarks it holds
+type Collection struct {
+    Name  string
+    Count int
+}
+
+// Struct to hold a user's bookmark collections and the posts of the collection being viewed
+type Bookmarks struct {
+    Collections []Collection  // sorted by name
+    Current     string
+    Posts       []Post        // most recently bookmarked first
+}
+
+// Checks if a collection name is not empty and not too long
+func ValidCollectionName(name string) bool {
+    return strings.TrimSpace(name) != "" && utf8.RuneCountInString(name) <= MAX_COLLECTION_NAME_LENGTH
+}
+
+// Bookmarks the post in the given collection, creating the collection if needed
+// Returns false if the user has too many collections or the collection is full
+// Bookmarking a post already in the collection moves it to the front
+func (user *UserInfo) Bookmark(collection string, ref PostRef) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    refs, ok := user.Bookmarks[collection]
+    if !ok && len(user.Bookmarks) >= MAX_COLLECTIONS {
+        return false
+    }
+    refs = removeRef(refs, ref)
+    if len(refs) >= MAX_BOOKMARKS {
+        return false
+    }
+    user.Bookmarks[collection] = append([]PostRef{ref}, refs...)
+    return true
+}
+
+// Removes the post from the given collection, empty collections are removed
+// Returns false if the post was not bookmarked in the collection
+func (user *UserInfo) RemoveBookmark(collection string, ref PostRef) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    refs, ok := user.Bookmarks[collection]
+    if !ok {
+        return false
+    }
+    remaining := removeRef(refs, ref)
+    if len(remaining) == len(refs) {
+        return false
+    }
+    if len(remaining) == 0 {
+        delete(user.Bookmarks, collection)
+    } else {
+        user.Bookmarks[collection] = remaining
+    }
+    return true
+}
+
+// Removes every bookmark the remove function returns true for, returns true if any were removed
+// Used to keep bookmarks valid when posts or whole accounts are deleted
+func (user *UserInfo) RemoveBookmarksWhere(remove func(PostRef) bool) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    changed := false
+    for collection, refs := range user.Bookmarks {
+        kept := []PostRef{}
+        for _, ref := range refs {
+            if remove(ref) {
+                changed = true
+            } else {
+                kept = append(kept, ref)
+            }
+        }
+        if len(kept) == 0 {
+            delete(user.Bookmarks, collection)
+        } else {
+            user.Bookmarks[collection] = kept
+        }
+    }
+    return changed
+}
+
+/*
+    Get bookmarks returns the user's collections and the posts bookmarked in the given collection.
+    Posts that no longer exist, have expired or are no longer visible to the user, because the poster
+    went private, one of them blocked the other or the post is hidden by its visibility, are left out.
+*/
+func (user *UserInfo) GetBookmarks(collection string, USERS map[string]*UserInfo) Bookmarks {
+    user.mut.Lock()
+    bookmarks := Bookmarks{Collections: []Collection{}, Current: collection, Posts: []Post{}}
+    for name, refs := range user.Bookmarks {
+        bookmarks.Collections = append(bookmarks.Collections, Collection{name, len(refs)})
+    }
+    refs := make([]PostRef, len(user.Bookmarks[collection]))
+    copy(refs, user.Bookmarks[collection])
+    user.mut.Unlock()
+    sort.Slice(bookmarks.Collections, func(i, j int) bool {
+        return bookmarks.Collections[i].Name < bookmarks.Collections[j].Name
+    })
+
+    now := time.Now()
+    for _, ref := range refs {
+        poster, ok := USERS[ref.Poster]
+        if !ok {
+            continue
+        }
+        if post, ok := poster.GetPost(ref.Id); ok && !post.Expired(now) && poster.CanSeePost(user, post) {
+            bookmarks.Posts = append(bookmarks.Posts, post)
+        }
+    }
+    return bookmarks
+}
+
+// Features one of the user's own posts on their profile, an id of 0 unpins the current post
+// Returns false if the user has no post with the id
+func (user *UserInfo) Pin(id int) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    if id != 0 {
+        found := false
+        for _, post := range user.Posts {
+            found = found || post.Id == id
+        }
+        if !found {
+            return false
+        }
+    }
+    user.Pinned = id
+    return true
+}
+
+// Returns the slice without the given post reference
+func removeRef(refs []PostRef, ref PostRef) []PostRef {
+    kept := []PostRef{}
+    for _, other := range refs {
+        if other != ref {
+            kept = append(kept, other)
+        }
+    }
+    return kept
+}
@@ -0,0 +1,90 @@
+package lib
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestBookmarkCollections(t *testing.T) {
+    user := NewUserInfo("alice", "")
+    for _, ref := range []PostRef{{"bob", 1}, {"bob", 2}, {"carol", 1}, {"bob", 1}} {
+        if !user.Bookmark(DEFAULT_COLLECTION, ref) {
+            t.Errorf("could not bookmark %v", ref)
+        }
+    }
+    want := []PostRef{{"bob", 1}, {"carol", 1}, {"bob", 2}}
+    if got := user.Bookmarks[DEFAULT_COLLECTION]; !reflect.DeepEqual(got, want) {
+        t.Errorf("bookmarks = %v, want %v with the re-bookmarked post first", got, want)
+    }
+
+    user.Bookmark("recipes", PostRef{"carol", 5})
+    if !user.RemoveBookmark("recipes", PostRef{"carol", 5}) || user.RemoveBookmark("recipes", PostRef{"carol", 5}) {
+        t.Errorf("the bookmark should be removed exactly once")
+    }
+    if _, ok := user.Bookmarks["recipes"]; ok {
+        t.Errorf("an empty collection was kept")
+    }
+
+    if !user.RemoveBookmarksWhere(func(ref PostRef) bool { return ref.Poster == "bob" }) {
+        t.Errorf("RemoveBookmarksWhere reported no change")
+    }
+    if got := user.Bookmarks[DEFAULT_COLLECTION]; !reflect.DeepEqual(got, []PostRef{{"carol", 1}}) {
+        t.Errorf("bookmarks after removing bob's = %v", got)
+    }
+    if user.RemoveBookmarksWhere(func(ref PostRef) bool { return ref.Poster == "bob" }) {
+        t.Errorf("RemoveBookmarksWhere reported a change the second time")
+    }
+
+    for i := len(user.Bookmarks); i < MAX_COLLECTIONS; i++ {
+        user.Bookmark(string(rune('a' + i)), PostRef{"carol", 1})
+    }
+    if user.Bookmark("one too many", PostRef{"carol", 1}) {
+        t.Errorf("created more than %d collections", MAX_COLLECTIONS)
+    }
+}
+
+func TestGetBookmarks(t *testing.T) {
+    now := time.Now()
+    alice, bob, carol := NewUserInfo("alice", ""), NewUserInfo("bob", ""), NewUserInfo("carol", "")
+    bob.Posts = []Post{
+        {Id: 1, Poster: "bob", Message: "public"},
+        {Id: 2, Poster: "bob", Message: "followers", Visibility: VisibilityFollowers},
+        {Id: 3, Poster: "bob", Message: "expired", ExpiresAt: now.Add(-time.Minute)},
+    }
+    carol.Posts = []Post{{Id: 1, Poster: "carol", Message: "blocked"}}
+    carol.Blocked["alice"] = true
+    USERS := map[string]*UserInfo{"alice": alice, "bob": bob, "carol": carol}
+    for _, ref := range []PostRef{{"ghost", 1}, {"carol", 1}, {"bob", 3}, {"bob", 2}, {"bob", 9}, {"bob", 1}} {
+        alice.Bookmark(DEFAULT_COLLECTION, ref)
+    }
+    alice.Bookmark("later", PostRef{"bob", 1})
+
+    bookmarks := alice.GetBookmarks(DEFAULT_COLLECTION, USERS)
+    if !reflect.DeepEqual(bookmarks.Collections, []Collection{{"Saved", 6}, {"later", 1}}) {
+        t.Errorf("collections = %+v", bookmarks.Collections)
+    }
+    if len(bookmarks.Posts) != 1 || bookmarks.Posts[0].Message != "public" {
+        t.Errorf("posts = %+v, want only the public post", bookmarks.Posts)
+    }
+
+    alice.Follow(bob)
+    bookmarks = alice.GetBookmarks(DEFAULT_COLLECTION, USERS)
+    if len(bookmarks.Posts) != 2 || bookmarks.Posts[0].Message != "public" || bookmarks.Posts[1].Message != "followers" {
+        t.Errorf("posts for a follower = %+v, want public then followers", bookmarks.Posts)
+    }
+}
+
+func TestPin(t *testing.T) {
+    user := NewUserInfo("alice", "")
+    user.Posts = []Post{{Id: 1}, {Id: 2}}
+    if !user.Pin(2) || user.Pinned != 2 {
+        t.Errorf("could not pin post 2")
+    }
+    if user.Pin(3) || user.Pinned != 2 {
+        t.Errorf("pinned a post that does not exist")
+    }
+    if !user.Pin(0) || user.Pinned != 0 {
+        t.Errorf("could not unpin")
+    }
+}
@@ -0,0 +1,47 @@
+package lib
+
+import (
+    "time"
+)
+
+// Marks the user's account deleted, it is hidden from everyone until it is restored or purged
+func (user *UserInfo) MarkDeleted(now time.Time) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    user.DeletedAt = now
+}
+
+// Checks if the user's account is waiting to be purged
+func (user *UserInfo) IsDeleted() bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return !user.DeletedAt.IsZero()
+}
+
+// Restores the user's deleted account, returns false if the account is not deleted
+func (user *UserInfo) RestoreAccount() bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    if user.DeletedAt.IsZero() {
+        return false
+    }
+    user.DeletedAt = time.Time{}
+    return true
+}
+
+// Returns when the user's deleted account is purged after the grace period, zero if it is not deleted
+func (user *UserInfo) PurgeTime(grace time.Duration) time.Time {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    if user.DeletedAt.IsZero() {
+        return time.Time{}
+    }
+    return user.DeletedAt.Add(grace)
+}
+
+// Checks if the user's account is hidden from other users, because it is suspended or deleted
+func (user *UserInfo) IsHidden() bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return user.Suspension.Active || !user.DeletedAt.IsZero()
+}
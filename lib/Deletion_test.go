@@ -0,0 +1,46 @@
+package lib
+
+import (
+    "testing"
+    "time"
+)
+
+func TestDeletionAndRestore(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    grace := 30 * 24 * time.Hour
+    user, viewer := NewUserInfo("alice", ""), NewUserInfo("bob", "")
+    user.Posts = []Post{{Id: 1, Poster: "alice"}}
+
+    if user.IsDeleted() || user.IsHidden() || !user.PurgeTime(grace).IsZero() || user.RestoreAccount() {
+        t.Errorf("a live account looks deleted")
+    }
+
+    user.MarkDeleted(now)
+    if !user.IsDeleted() || !user.IsHidden() {
+        t.Errorf("deleted account is not hidden")
+    }
+    if got := user.PurgeTime(grace); !got.Equal(now.Add(grace)) {
+        t.Errorf("PurgeTime = %v, want %v", got, now.Add(grace))
+    }
+    if user.VisibleTo(viewer) || user.CanSeePost(viewer, user.Posts[0]) || !user.VisibleTo(user) {
+        t.Errorf("deleted account is visible to other users, or hidden from itself")
+    }
+    if posts := mergeChirps(viewer, []*UserInfo{user}); len(posts) != 0 {
+        t.Errorf("timeline shows %d posts of a deleted account", len(posts))
+    }
+
+    if !user.RestoreAccount() || user.RestoreAccount() {
+        t.Errorf("the account should be restored exactly once")
+    }
+    if user.IsDeleted() || user.IsHidden() || !user.VisibleTo(viewer) || !user.PurgeTime(grace).IsZero() {
+        t.Errorf("restored account is still hidden")
+    }
+    if len(user.Posts) != 1 {
+        t.Errorf("restoring lost the account's posts")
+    }
+
+    user.Suspend("spam")
+    if !user.IsHidden() || user.IsDeleted() {
+        t.Errorf("suspended account: hidden %v, deleted %v, want hidden only", user.IsHidden(), user.IsDeleted())
+    }
+}
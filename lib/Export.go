@@ -0,0 +1,271 @@
+package lib
+
+import (
+    "archive/zip"
+    "encoding/json"
+    "html/template"
+    "io"
+    "io/ioutil"
+    "os"
+    "sort"
+    "time"
+)
+
+const EXPORT_DIR = "../../data/exports"  // directory holding the latest archive of each user who asked for one
+
+// States of a user's data export
+const (
+    ExportNone = iota  // no archive has been asked for
+    ExportRunning      // the archive is being written
+    ExportReady        // the archive can be downloaded
+    ExportFailed       // writing the archive failed, a new one can be asked for
+)
+
+// Struct to hold the state of a user's data export
+type ExportStatus struct {
+    State int
+    Time  string  // when the archive was asked for
+    Size  int64   // size of the finished archive in bytes
+}
+
+// Struct to hold the profile part of an archive
+type ArchiveProfile struct {
+    Username    string
+    DisplayName string
+    Bio         string
+    Joined      time.Time
+    Private     bool
+    Role        string
+}
+
+/*
+    Archive holds everything a user's data export contains: their profile, posts, the users they follow and
+    who follow them, their lists, bookmarks and direct messages.
+    Chirps cannot be liked, so bookmarks are the closest record of the posts a user chose to keep.
+    Other users' data is only included where the user is part of it, such as messages in a shared conversation.
+*/
+type Archive struct {
+    ExportedAt    time.Time
+    Profile       ArchiveProfile
+    Posts         []Post
+    Following     []string
+    Followers     []string
+    Lists         []List
+    Bookmarks     map[string][]PostRef
+    Conversations []Conversation
+}
+
+// Returns a copy of the user's data for an archive, taken while the user is locked and sharing nothing with it
+func (user *UserInfo) Archive(now time.Time) Archive {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    archive := Archive{
+        ExportedAt: now,
+        Profile:    ArchiveProfile{user.Username, user.DisplayName, user.Bio, user.Joined, user.Private, RoleName(user.Role)},
+        Posts:      make([]Post, len(user.Posts)),
+        Followers:  append([]string{}, user.FollowedBy...),
+        Bookmarks:  make(map[string][]PostRef),
+    }
+    for i, post := range user.Posts {
+        archive.Posts[i] = post.deepCopy()
+    }
+    for name := range user.Following {
+        archive.Following = append(archive.Following, name)
+    }
+    sort.Strings(archive.Following)
+    sort.Strings(archive.Followers)
+    for _, list := range user.Lists {
+        archive.Lists = append(archive.Lists, List{list.Name, list.Private, append([]string{}, list.Members...)})
+    }
+    sort.Slice(archive.Lists, func(i, j int) bool {
+        return archive.Lists[i].Name < archive.Lists[j].Name
+    })
+    for name, refs := range user.Bookmarks {
+        archive.Bookmarks[name] = append([]PostRef{}, refs...)
+    }
+    for _, conv := range user.Conversations {
+        messages := append([]Message{}, conv.Messages...)
+        for i := range messages {
+            messages[i].Tags = append([]string(nil), messages[i].Tags...)
+        }
+        archive.Conversations = append(archive.Conversations, Conversation{conv.Key, append([]string{}, conv.Members...), messages})
+    }
+    sort.Slice(archive.Conversations, func(i, j int) bool {
+        return archive.Conversations[i].Key < archive.Conversations[j].Key
+    })
+    return archive
+}
+
+// Returns a copy of the post sharing no slices with it, so the copy can be read after the user is unlocked
+func (post Post) deepCopy() Post {
+    post.Mentions = append([]string(nil), post.Mentions...)
+    post.Attachments = append([]Attachment(nil), post.Attachments...)
+    post.Poll.Options = append([]string(nil), post.Poll.Options...)
+    post.Poll.Counts = append([]int(nil), post.Poll.Counts...)
+    post.Tags = append([]string(nil), post.Tags...)
+    return post
+}
+
+// Returns the path of the user's archive, named by the storage key of the username
+func ExportPath(username string) string {
+    return EXPORT_DIR + "/" + StorageKey(username) + ".zip"
+}
+
+/*
+    Write archive stores the archive as a zip file holding archive.json, a readable archive.html and the
+    attachments of the user's posts under media/, named by their hash as in the JSON.
+    The file is written under a temporary name and renamed when complete, so a download never sees a
+    half written archive. Returns the size of the archive.
+*/
+func WriteArchive(archive Archive) (int64, error) {
+    if err := os.MkdirAll(EXPORT_DIR, os.ModePerm); err != nil {
+        return 0, err
+    }
+    path := ExportPath(archive.Profile.Username)
+    temp := path + ".tmp"
+    file, err := os.Create(temp)
+    if err != nil {
+        return 0, err
+    }
+    defer os.Remove(temp)
+
+    writer := zip.NewWriter(file)
+    err = writeArchiveFiles(writer, archive)
+    if closeErr := writer.Close(); err == nil {
+        err = closeErr
+    }
+    if closeErr := file.Close(); err == nil {
+        err = closeErr
+    }
+    if err != nil {
+        return 0, err
+    }
+    info, err := os.Stat(temp)
+    if err != nil {
+        return 0, err
+    }
+    return info.Size(), os.Rename(temp, path)
+}
+
+// Writes the entries of the archive to the zip writer
+func writeArchiveFiles(writer *zip.Writer, archive Archive) error {
+    data, err := json.MarshalIndent(archive, "", "  ")
+    if err != nil {
+        return err
+    }
+    entry, err := createEntry(writer, "archive.json", archive.ExportedAt)
+    if err != nil {
+        return err
+    }
+    if _, err = entry.Write(data); err != nil {
+        return err
+    }
+
+    entry, err = createEntry(writer, "archive.html", archive.ExportedAt)
+    if err != nil {
+        return err
+    }
+    if err = archiveTemplate.Execute(entry, archive); err != nil {
+        return err
+    }
+
+    written := make(map[string]bool)
+    for _, post := range archive.Posts {
+        for _, attachment := range post.Attachments {
+            if written[attachment.Hash] {
+                continue
+            }
+            written[attachment.Hash] = true
+            blob, err := ReadBlob(attachment.Hash)
+            if err != nil {
+                continue  // a missing blob leaves the attachment listed in the JSON without its contents
+            }
+            entry, err := createEntry(writer, "media/" + attachment.Hash, post.Stamp)
+            if err != nil {
+                return err
+            }
+            if _, err = entry.Write(blob); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// Adds a compressed file to the zip writer dated with the given time
+func createEntry(writer *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+    return writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+}
+
+// Reads the user's finished archive
+func ReadArchive(username string) ([]byte, error) {
+    return ioutil.ReadFile(ExportPath(username))
+}
+
+// Removes the user's archive, if any
+func RemoveArchive(username string) {
+    os.Remove(ExportPath(username))
+}
+
+// Moves the user's archive to their new username, if any
+func RenameArchive(old, name string) {
+    os.Rename(ExportPath(old), ExportPath(name))
+}
+
+// Readable version of the archive, links to attachments point into the media folder of the archive
+var archiveTemplate = template.Must(template.New("archive").Parse(`<!doctype html>
+<html>
+    <head>
+        <meta charset="UTF-8">
+        <title>Chirp archive of @{{.Profile.Username}}</title>
+    </head>
+    <body>
+        <h1>@{{.Profile.Username}}</h1>
+        {{with .Profile.DisplayName}}{{.}}<br>{{end}}
+        {{with .Profile.Bio}}{{.}}<br>{{end}}
+        Joined {{.Profile.Joined.Format "2 January 2006"}}{{if .Profile.Private}}, private account{{end}}<br>
+        Exported {{.ExportedAt.Format "2 January 2006 15:04 MST"}}
+
+        <h2>Chirps</h2>
+        {{range .Posts}}
+        {{.Time}}{{with .Audience}} &emsp; {{.}}{{end}}{{if .TakenDown}} &emsp; Removed by moderators{{end}}<br>
+        {{.Message}}<br>
+        {{range .Attachments}}<a href="media/{{.Hash}}">{{.Name}}</a><br>{{end}}
+        {{$counts := .Poll.Counts}}{{range $i, $option := .Poll.Options}}&emsp; {{$option}}: {{index $counts $i}}<br>{{end}}
+        <br>
+        {{else}}
+        No chirps<br>
+        {{end}}
+
+        <h2>Following</h2>
+        {{range .Following}}@{{.}}<br>{{else}}Nobody<br>{{end}}
+
+        <h2>Followers</h2>
+        {{range .Followers}}@{{.}}<br>{{else}}Nobody<br>{{end}}
+
+        <h2>Lists</h2>
+        {{range .Lists}}{{.Name}}{{if .Private}} (private){{end}}: {{range .Members}}@{{.}} {{end}}<br>{{else}}No lists<br>{{end}}
+
+        <h2>Bookmarks</h2>
+        {{range $name, $refs := .Bookmarks}}{{$name}}: {{range $refs}}@{{.Poster}} chirp {{.Id}} &emsp; {{end}}<br>{{else}}No bookmarks<br>{{end}}
+
+        <h2>Messages</h2>
+        {{range .Conversations}}
+        <h3>{{range .Members}}@{{.}} {{end}}</h3>
+        {{range .Messages}}{{.Time}} &emsp; @{{.Sender}}: {{.Message}}<br>{{end}}
+        {{else}}
+        No messages<br>
+        {{end}}
+    </body>
+</html>
+`))
+
+// Returns the status of an archive written before the server started, false if there is none
+func FinishedExport(username string) (ExportStatus, bool) {
+    info, err := os.Stat(ExportPath(username))
+    if err != nil {
+        return ExportStatus{}, false
+    }
+    stamp := info.ModTime().Format(time.RFC1123)[0:len(time.RFC1123)-4]
+    return ExportStatus{ExportReady, stamp, info.Size()}, true
+}
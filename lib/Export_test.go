@@ -0,0 +1,99 @@
+package lib
+
+import (
+    "archive/zip"
+    "bytes"
+    "encoding/json"
+    "io/ioutil"
+    "reflect"
+    "testing"
+    "time"
+)
+
+// Builds a user with something in every part of an archive
+func exportingUser(now time.Time) *UserInfo {
+    user := NewUserInfo("alice", "secret hash")
+    user.DisplayName = "Alice"
+    user.Joined = now.Add(-24 * time.Hour)
+    user.Following["carol"] = true
+    user.Following["bob"] = true
+    user.FollowedBy = []string{"dave", "bob"}
+    user.Lists["friends"] = &List{"friends", true, []string{"bob"}}
+    user.Bookmarks[DEFAULT_COLLECTION] = []PostRef{{"bob", 3}}
+    user.Posts = []Post{
+        {Id: 1, Poster: "alice", Message: "hello @bob", Stamp: now, Mentions: []string{"bob"}, Tags: []string{"link"}},
+        {Id: 2, Poster: "alice", Message: "tea or coffee", Stamp: now, Poll: Poll{Options: []string{"tea", "coffee"}, Counts: []int{1, 2}}},
+    }
+    user.Conversations["alice,bob"] = &Conversation{"alice,bob", []string{"alice", "bob"}, []Message{{Sender: "bob", Message: "hi"}}}
+    return user
+}
+
+func TestArchiveContents(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    user := exportingUser(now)
+    archive := user.Archive(now)
+
+    if archive.Profile.Username != "alice" || archive.Profile.DisplayName != "Alice" || archive.Profile.Role != RoleName(RoleUser) {
+        t.Errorf("profile = %+v", archive.Profile)
+    }
+    if !reflect.DeepEqual(archive.Following, []string{"bob", "carol"}) {
+        t.Errorf("following = %v, want sorted [bob carol]", archive.Following)
+    }
+    if !reflect.DeepEqual(archive.Followers, []string{"bob", "dave"}) {
+        t.Errorf("followers = %v, want sorted [bob dave]", archive.Followers)
+    }
+    if len(archive.Posts) != 2 || len(archive.Lists) != 1 || len(archive.Bookmarks[DEFAULT_COLLECTION]) != 1 || len(archive.Conversations) != 1 {
+        t.Errorf("archive is missing data: %+v", archive)
+    }
+
+    var buf bytes.Buffer
+    writer := zip.NewWriter(&buf)
+    if err := writeArchiveFiles(writer, archive); err != nil {
+        t.Fatal(err)
+    }
+    writer.Close()
+    reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+    if err != nil {
+        t.Fatal(err)
+    }
+    files := make(map[string][]byte)
+    for _, file := range reader.File {
+        entry, _ := file.Open()
+        files[file.Name], _ = ioutil.ReadAll(entry)
+        entry.Close()
+    }
+    if _, ok := files["archive.html"]; !ok || len(files) != 2 {
+        t.Errorf("zip holds %d files, want archive.json and archive.html", len(files))
+    }
+    if bytes.Contains(files["archive.json"], []byte("secret hash")) {
+        t.Errorf("archive.json contains the password hash")
+    }
+    var decoded Archive
+    if err := json.Unmarshal(files["archive.json"], &decoded); err != nil {
+        t.Fatal(err)
+    }
+    if decoded.Posts[1].Poll.Counts[1] != 2 || decoded.Conversations[0].Messages[0].Message != "hi" {
+        t.Errorf("archive.json does not match the archive: %+v", decoded)
+    }
+}
+
+func TestArchiveSharesNothingWithTheUser(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    user := exportingUser(now)
+    archive := user.Archive(now)
+
+    user.Posts[0].Mentions[0] = "changed"
+    user.Posts[0].Tags[0] = "changed"
+    user.Posts[1].Poll.Counts[0] = 99
+    user.Posts[1].Poll.Options[0] = "changed"
+    user.Conversations["alice,bob"].Messages[0].Message = "changed"
+    if archive.Posts[0].Mentions[0] != "bob" || archive.Posts[0].Tags[0] != "link" {
+        t.Errorf("archived post changed with the user: %+v", archive.Posts[0])
+    }
+    if archive.Posts[1].Poll.Counts[0] != 1 || archive.Posts[1].Poll.Options[0] != "tea" {
+        t.Errorf("archived poll changed with the user: %+v", archive.Posts[1].Poll)
+    }
+    if archive.Conversations[0].Messages[0].Message != "hi" {
+        t.Errorf("archived message changed with the user")
+    }
+}
@@ -0,0 +1,298 @@
+package lib
+
+import (
+    "encoding/json"
+    "errors"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+const FILTER_CONFIG = "../../config/filters.json"  // rules for the filter chain, edited by admins
+
+// Verdicts of the filter chain, from mildest to strictest
+const (
+    FilterPass = iota    // the post is stored as written
+    FilterTag            // the post is stored with a label shown to readers
+    FilterHold           // the post is stored but only its author sees it until an admin approves it
+    FilterReject         // the post is not stored
+)
+
+var filterActions = map[string]int{"tag": FilterTag, "hold": FilterHold, "reject": FilterReject}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(https?://|www\.)\S+`)  // matches links in a post
+
+// Struct to hold one rule of the filter configuration, the fields used depend on the type
+type FilterRule struct {
+    Type    string   `json:"type"`             // "banned_words", "links", "repeat" or "velocity"
+    Action  string   `json:"action"`           // "reject", "hold" or "tag"
+    Tag     string   `json:"tag,omitempty"`    // label added by the tag action, the type is used if empty
+    Words   []string `json:"words,omitempty"`  // banned words and phrases
+    Max     int      `json:"max,omitempty"`    // most links in a post, or most posts in the window
+    Minutes int      `json:"minutes,omitempty"`  // window for repeated posts and posting velocity
+}
+
+// Struct to hold the filter configuration file
+type FilterConfig struct {
+    Filters []FilterRule `json:"filters"`
+}
+
+// Struct to hold a chirp or direct message about to be written, passed to every filter
+type Draft struct {
+    Author  *UserInfo
+    Message string
+    Direct  bool  // a direct message rather than a chirp
+    Now     time.Time
+}
+
+// Struct to hold the outcome of running the filter chain on a draft
+type FilterResult struct {
+    Verdict int
+    Reason  string    // why the strictest filter matched
+    Tags    []string  // labels added by tag actions
+}
+
+/*
+    Filter is implemented by every kind of filter in the chain. Check reports whether the draft matches
+    the filter and why, the chain decides what to do with a match from the rule's action.
+    A new kind of filter is added by implementing Filter and registering a constructor in filterTypes.
+*/
+type Filter interface {
+    Check(draft Draft) (bool, string)
+}
+
+// Constructors for each filter type named in the configuration
+var filterTypes = map[string]func(FilterRule) (Filter, error){
+    "banned_words": newBannedWords,
+    "links":        newLinkFlood,
+    "repeat":       newRepeatPost,
+    "velocity":     newVelocity,
+}
+
+// Struct to hold a filter with the action taken when it matches
+type chainEntry struct {
+    filter  Filter
+    verdict int
+    tag     string
+}
+
+// Struct to hold the filter chain run on every new chirp and direct message, safe to reload while in use
+type FilterChain struct {
+    entries []chainEntry
+    config  string  // the configuration the chain was built from
+    mut     *sync.RWMutex
+}
+
+// Creates an empty filter chain that passes everything
+func NewFilterChain() *FilterChain {
+    return &FilterChain{mut: &sync.RWMutex{}}
+}
+
+// Replaces the chain with the filters of the given JSON configuration, an empty configuration removes
+// every filter
+// The chain is left unchanged if the configuration is invalid
+func (chain *FilterChain) Load(config string) error {
+    var parsed FilterConfig
+    if strings.TrimSpace(config) != "" {
+        if err := json.Unmarshal([]byte(config), &parsed); err != nil {
+            return err
+        }
+    }
+    var entries []chainEntry
+    for i, rule := range parsed.Filters {
+        build, ok := filterTypes[rule.Type]
+        if !ok {
+            return errors.New("filter " + strconv.Itoa(i + 1) + ": unknown type " + rule.Type)
+        }
+        verdict, ok := filterActions[rule.Action]
+        if !ok {
+            return errors.New("filter " + strconv.Itoa(i + 1) + ": unknown action " + rule.Action)
+        }
+        filter, err := build(rule)
+        if err != nil {
+            return errors.New("filter " + strconv.Itoa(i + 1) + ": " + err.Error())
+        }
+        tag := rule.Tag
+        if tag == "" {
+            tag = rule.Type
+        }
+        entries = append(entries, chainEntry{filter, verdict, tag})
+    }
+    chain.mut.Lock()
+    defer chain.mut.Unlock()
+    chain.entries = entries
+    chain.config = config
+    return nil
+}
+
+// Returns the configuration the chain was built from
+func (chain *FilterChain) Config() string {
+    chain.mut.RLock()
+    defer chain.mut.RUnlock()
+    return chain.config
+}
+
+// Runs every filter on the draft, the strictest verdict of the matching filters wins
+func (chain *FilterChain) Run(draft Draft) FilterResult {
+    chain.mut.RLock()
+    defer chain.mut.RUnlock()
+    result := FilterResult{Verdict: FilterPass}
+    for _, entry := range chain.entries {
+        matched, reason := entry.filter.Check(draft)
+        if !matched {
+            continue
+        }
+        if entry.verdict == FilterTag {
+            result.Tags = append(result.Tags, entry.tag)
+        }
+        if entry.verdict > result.Verdict {
+            result.Verdict = entry.verdict
+            result.Reason = reason
+        }
+    }
+    return result
+}
+
+// Marks the post held for review or adds the tags according to the result
+func (result FilterResult) Apply(post *Post) {
+    if result.Verdict == FilterHold {
+        post.Held = true
+        post.HeldFor = result.Reason
+    }
+    post.Tags = result.Tags
+}
+
+// Reads the filter configuration file, a missing file is an empty configuration
+func ReadFilterConfig() (string, error) {
+    data, err := ioutil.ReadFile(FILTER_CONFIG)
+    if os.IsNotExist(err) {
+        return "", nil
+    }
+    return string(data), err
+}
+
+// Writes the filter configuration file through a temporary file so it is never left half written
+func WriteFilterConfig(config string) error {
+    if err := os.MkdirAll(filepath.Dir(FILTER_CONFIG), os.ModePerm); err != nil {
+        return err
+    }
+    temp := FILTER_CONFIG + ".tmp"
+    if err := ioutil.WriteFile(temp, []byte(config), 0644); err != nil {
+        return err
+    }
+    return os.Rename(temp, FILTER_CONFIG)
+}
+
+// Returns the messages the user wrote since the given time, chirps or direct messages they sent
+func (user *UserInfo) RecentWrites(since time.Time, direct bool) []string {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    var messages []string
+    if !direct {
+        for i := len(user.Posts) - 1; i >= 0 && user.Posts[i].Stamp.After(since); i-- {
+            messages = append(messages, user.Posts[i].Message)
+        }
+        return messages
+    }
+    for _, conv := range user.Conversations {
+        for i := len(conv.Messages) - 1; i >= 0 && conv.Messages[i].Stamp.After(since); i-- {
+            if conv.Messages[i].Sender == user.Username {
+                messages = append(messages, conv.Messages[i].Message)
+            }
+        }
+    }
+    return messages
+}
+
+// Filter matching posts that contain a banned word or phrase, ignoring case
+type bannedWords struct {
+    phrases [][]string
+}
+
+func newBannedWords(rule FilterRule) (Filter, error) {
+    var phrases [][]string
+    for _, word := range rule.Words {
+        if tokens := Tokenize(word); len(tokens) > 0 {
+            phrases = append(phrases, tokens)
+        }
+    }
+    if len(phrases) == 0 {
+        return nil, errors.New("banned_words needs words")
+    }
+    return bannedWords{phrases}, nil
+}
+
+func (filter bannedWords) Check(draft Draft) (bool, string) {
+    tokens := Tokenize(draft.Message)
+    for _, phrase := range filter.phrases {
+        if containsPhrase(tokens, phrase) {
+            return true, "Contains a banned word"
+        }
+    }
+    return false, ""
+}
+
+// Filter matching posts with more links than allowed
+type linkFlood struct {
+    max int
+}
+
+func newLinkFlood(rule FilterRule) (Filter, error) {
+    if rule.Max < 0 {
+        return nil, errors.New("links needs a max of 0 or more")
+    }
+    return linkFlood{rule.Max}, nil
+}
+
+func (filter linkFlood) Check(draft Draft) (bool, string) {
+    if len(linkPattern.FindAllString(draft.Message, -1)) > filter.max {
+        return true, "Too many links"
+    }
+    return false, ""
+}
+
+// Filter matching posts repeating one of the author's posts from the last few minutes, ignoring case
+type repeatPost struct {
+    window time.Duration
+}
+
+func newRepeatPost(rule FilterRule) (Filter, error) {
+    if rule.Minutes <= 0 {
+        return nil, errors.New("repeat needs minutes")
+    }
+    return repeatPost{time.Duration(rule.Minutes) * time.Minute}, nil
+}
+
+func (filter repeatPost) Check(draft Draft) (bool, string) {
+    for _, message := range draft.Author.RecentWrites(draft.Now.Add(-filter.window), draft.Direct) {
+        if strings.EqualFold(message, draft.Message) {
+            return true, "Repeats a recent post"
+        }
+    }
+    return false, ""
+}
+
+// Filter matching authors who already posted the maximum number of times in the last few minutes
+type velocity struct {
+    max    int
+    window time.Duration
+}
+
+func newVelocity(rule FilterRule) (Filter, error) {
+    if rule.Max <= 0 || rule.Minutes <= 0 {
+        return nil, errors.New("velocity needs max and minutes")
+    }
+    return velocity{rule.Max, time.Duration(rule.Minutes) * time.Minute}, nil
+}
+
+func (filter velocity) Check(draft Draft) (bool, string) {
+    if len(draft.Author.RecentWrites(draft.Now.Add(-filter.window), draft.Direct)) >= filter.max {
+        return true, "Posting too fast"
+    }
+    return false, ""
+}
@@ -0,0 +1,99 @@
+package lib
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+const testFilterConfig = `{"filters": [
+    {"type": "banned_words", "action": "reject", "words": ["buy now", "scam"]},
+    {"type": "links", "action": "hold", "max": 1},
+    {"type": "links", "action": "tag", "tag": "link", "max": 0},
+    {"type": "repeat", "action": "reject", "minutes": 10},
+    {"type": "velocity", "action": "hold", "max": 3, "minutes": 5}
+]}`
+
+func TestFilterChainRun(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    chain := NewFilterChain()
+    if err := chain.Load(testFilterConfig); err != nil {
+        t.Fatal(err)
+    }
+    author := NewUserInfo("alice", "")
+    author.Posts = []Post{{Id: 1, Message: "Hello world", Stamp: now.Add(-time.Minute)}}
+    author.AddMessage([]string{"alice", "bob"}, "alice", "same again", nil, now.Add(-time.Minute))
+
+    tests := []struct {
+        name    string
+        message string
+        direct  bool
+        want    FilterResult
+    }{
+        {"clean", "good morning", false, FilterResult{FilterPass, "", nil}},
+        {"banned phrase", "BUY NOW while it lasts", false, FilterResult{FilterReject, "Contains a banned word", nil}},
+        {"words apart", "buy it now", false, FilterResult{FilterPass, "", nil}},
+        {"one link", "see https://example.com", false, FilterResult{FilterTag, "Too many links", []string{"link"}}},
+        {"two links", "www.a.com and http://b.com", false, FilterResult{FilterHold, "Too many links", []string{"link"}}},
+        {"strictest wins", "scam at www.a.com www.b.com", false, FilterResult{FilterReject, "Contains a banned word", []string{"link"}}},
+        {"repeated chirp", "hello WORLD", false, FilterResult{FilterReject, "Repeats a recent post", nil}},
+        {"chirp repeated as a message", "hello world", true, FilterResult{FilterPass, "", nil}},
+        {"repeated message", "same again", true, FilterResult{FilterReject, "Repeats a recent post", nil}},
+    }
+    for _, test := range tests {
+        got := chain.Run(Draft{author, test.message, test.direct, now})
+        if !reflect.DeepEqual(got, test.want) {
+            t.Errorf("%s: Run(%q) = %+v, want %+v", test.name, test.message, got, test.want)
+        }
+    }
+
+    author.Posts = append(author.Posts, Post{Id: 2, Stamp: now.Add(-2 * time.Minute)}, Post{Id: 3, Stamp: now.Add(-time.Second)})
+    if got := chain.Run(Draft{author, "fourth", false, now}); got.Verdict != FilterHold || got.Reason != "Posting too fast" {
+        t.Errorf("fourth chirp in five minutes = %+v, want held for posting too fast", got)
+    }
+    if got := chain.Run(Draft{author, "later", false, now.Add(6 * time.Minute)}); got.Verdict != FilterPass {
+        t.Errorf("chirp after the window = %+v, want pass", got)
+    }
+}
+
+func TestFilterChainLoad(t *testing.T) {
+    chain := NewFilterChain()
+    if err := chain.Load(testFilterConfig); err != nil {
+        t.Fatal(err)
+    }
+    invalid := []string{
+        `{"filters": [`,
+        `{"filters": [{"type": "unknown", "action": "reject"}]}`,
+        `{"filters": [{"type": "links", "action": "delete"}]}`,
+        `{"filters": [{"type": "banned_words", "action": "reject", "words": ["!!"]}]}`,
+        `{"filters": [{"type": "repeat", "action": "reject"}]}`,
+        `{"filters": [{"type": "velocity", "action": "hold", "max": 3}]}`,
+    }
+    for _, config := range invalid {
+        if err := chain.Load(config); err == nil {
+            t.Errorf("Load(%s) accepted an invalid configuration", config)
+        }
+        if chain.Config() != testFilterConfig {
+            t.Errorf("Load(%s) changed the chain", config)
+        }
+    }
+    if err := chain.Load(""); err != nil || chain.Config() != "" {
+        t.Errorf("empty configuration: %v", err)
+    }
+    if got := chain.Run(Draft{NewUserInfo("alice", ""), "scam", false, time.Now()}); got.Verdict != FilterPass {
+        t.Errorf("empty chain = %+v, want pass", got)
+    }
+}
+
+func TestFilterResultApply(t *testing.T) {
+    var post Post
+    FilterResult{FilterHold, "Too many links", []string{"link"}}.Apply(&post)
+    if !post.Held || post.HeldFor != "Too many links" || !reflect.DeepEqual(post.Tags, []string{"link"}) {
+        t.Errorf("held post = %+v", post)
+    }
+    post = Post{}
+    FilterResult{FilterTag, "Too many links", []string{"link"}}.Apply(&post)
+    if post.Held || !reflect.DeepEqual(post.Tags, []string{"link"}) {
+        t.Errorf("tagged post = %+v", post)
+    }
+}
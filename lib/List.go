@@ -0,0 +1,146 @@
+package lib
+
+import (
+    "sort"
+    "strings"
+    "unicode/utf8"
+)
+
+const MAX_LISTS = 20             // most lists a user can have
+const MAX_LIST_NAME_LENGTH = 25  // longest list name in characters
+const MAX_LIST_MEMBERS = 500     // most users on a single list
+
+// Struct to hold a curated list of users
+type List struct {
+    Name    string
+    Private bool      // only the owner can see the list
+    Members []string  // sorted usernames
+}
+
+// Struct to hold a list and the timeline of its members as shown to a viewer
+type ListTimeline struct {
+    Owner string
+    List  List
+    Posts []Post  // newest first
+}
+
+// Checks if a list name is not empty and not too long
+func ValidListName(name string) bool {
+    return strings.TrimSpace(name) != "" && utf8.RuneCountInString(name) <= MAX_LIST_NAME_LENGTH
+}
+
+// Creates an empty list, returns false if the user already has a list with the name or has too many lists
+func (user *UserInfo) CreateList(name string, private bool) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    if _, ok := user.Lists[name]; ok || len(user.Lists) >= MAX_LISTS {
+        return false
+    }
+    user.Lists[name] = &List{Name: name, Private: private}
+    return true
+}
+
+// Deletes the list with the given name, returns false if there is none
+func (user *UserInfo) DeleteList(name string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    if _, ok := user.Lists[name]; !ok {
+        return false
+    }
+    delete(user.Lists, name)
+    return true
+}
+
+// Returns a copy of the list with the given name if the viewer is allowed to see it
+// Users blocked by the owner cannot see any of the owner's lists
+func (user *UserInfo) GetList(name, viewer string) (List, bool) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    list, ok := user.Lists[name]
+    if !ok || (viewer != user.Username && (list.Private || user.Blocked[viewer])) {
+        return List{}, false
+    }
+    return copyList(list), true
+}
+
+// Returns copies of the lists the viewer is allowed to see, sorted by name
+func (user *UserInfo) GetLists(viewer string) []List {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    lists := []List{}
+    if user.Blocked[viewer] {
+        return lists
+    }
+    for _, list := range user.Lists {
+        if !list.Private || viewer == user.Username {
+            lists = append(lists, copyList(list))
+        }
+    }
+    sort.Slice(lists, func(i, j int) bool {
+        return lists[i].Name < lists[j].Name
+    })
+    return lists
+}
+
+// Adds a username to the list, returns false if the list does not exist or is full
+// Adding a username already on the list does nothing
+func (user *UserInfo) AddToList(name, member string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    list, ok := user.Lists[name]
+    if !ok {
+        return false
+    }
+    i := sort.SearchStrings(list.Members, member)
+    if i < len(list.Members) && list.Members[i] == member {
+        return true
+    }
+    if len(list.Members) >= MAX_LIST_MEMBERS {
+        return false
+    }
+    list.Members = append(list.Members, "")
+    copy(list.Members[i+1:], list.Members[i:])
+    list.Members[i] = member
+    return true
+}
+
+// Removes a username from the list, returns false if the list does not exist
+func (user *UserInfo) RemoveFromList(name, member string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    list, ok := user.Lists[name]
+    if !ok {
+        return false
+    }
+    list.Members = removeName(list.Members, member)
+    return true
+}
+
+/*
+    List chirps returns the list with the given name and the posts of its members merged into one timeline.
+    Members the viewer cannot see are left out: private accounts the viewer does not follow, users blocked
+    by or blocking the viewer and users the viewer muted, as are posts whose visibility hides them from the
+    viewer. Returns false if the viewer cannot see the list.
+*/
+func (user *UserInfo) ListChirps(name string, viewer *UserInfo, USERS map[string]*UserInfo) (ListTimeline, bool) {
+    list, ok := user.GetList(name, viewer.Username)
+    if !ok {
+        return ListTimeline{}, false
+    }
+    var posters []*UserInfo
+    for _, name := range list.Members {
+        member, ok := USERS[name]
+        if !ok || !member.VisibleTo(viewer) || (member != viewer && (EitherBlocked(viewer, member) || viewer.HasMuted(name))) {
+            continue
+        }
+        posters = append(posters, member)
+    }
+    return ListTimeline{user.Username, list, mergeChirps(viewer, posters)}, true
+}
+
+// Copies a list so it can be used without holding the owner's lock
+func copyList(list *List) List {
+    members := make([]string, len(list.Members))
+    copy(members, list.Members)
+    return List{list.Name, list.Private, members}
+}
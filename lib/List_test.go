@@ -0,0 +1,110 @@
+package lib
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestValidListName(t *testing.T) {
+    tests := []struct {
+        name string
+        want bool
+    }{
+        {"friends", true},
+        {"", false},
+        {"   ", false},
+        {strings.Repeat("a", MAX_LIST_NAME_LENGTH), true},
+        {strings.Repeat("a", MAX_LIST_NAME_LENGTH + 1), false},
+    }
+    for _, test := range tests {
+        if got := ValidListName(test.name); got != test.want {
+            t.Errorf("ValidListName(%q) = %v, want %v", test.name, got, test.want)
+        }
+    }
+}
+
+func TestListMembersAndAccess(t *testing.T) {
+    user := NewUserInfo("alice", "")
+    if !user.CreateList("friends", false) || user.CreateList("friends", true) || !user.CreateList("secret", true) {
+        t.Errorf("each list should be created exactly once")
+    }
+    for _, member := range []string{"carol", "bob", "dave", "bob"} {
+        if !user.AddToList("friends", member) {
+            t.Errorf("could not add %s", member)
+        }
+    }
+    if user.AddToList("missing", "bob") || user.RemoveFromList("missing", "bob") {
+        t.Errorf("changed a list that does not exist")
+    }
+    user.RemoveFromList("friends", "dave")
+    if list, _ := user.GetList("friends", "alice"); !reflect.DeepEqual(list.Members, []string{"bob", "carol"}) {
+        t.Errorf("members = %q, want sorted [bob carol]", list.Members)
+    }
+
+    if _, ok := user.GetList("secret", "bob"); ok {
+        t.Errorf("a private list is visible to another user")
+    }
+    if _, ok := user.GetList("secret", "alice"); !ok {
+        t.Errorf("a private list is hidden from its owner")
+    }
+    names := func(lists []List) []string {
+        result := []string{}
+        for _, list := range lists {
+            result = append(result, list.Name)
+        }
+        return result
+    }
+    if got := names(user.GetLists("alice")); !reflect.DeepEqual(got, []string{"friends", "secret"}) {
+        t.Errorf("owner sees lists %q", got)
+    }
+    if got := names(user.GetLists("bob")); !reflect.DeepEqual(got, []string{"friends"}) {
+        t.Errorf("bob sees lists %q", got)
+    }
+    user.Blocked["bob"] = true
+    if _, ok := user.GetList("friends", "bob"); ok || len(user.GetLists("bob")) != 0 {
+        t.Errorf("a blocked user can see the owner's lists")
+    }
+
+    if !user.DeleteList("secret") || user.DeleteList("secret") {
+        t.Errorf("the list should be deleted exactly once")
+    }
+}
+
+func TestListChirps(t *testing.T) {
+    now := time.Now()
+    USERS := map[string]*UserInfo{}
+    for _, name := range []string{"alice", "bob", "carol", "dave", "erin", "viewer"} {
+        USERS[name] = NewUserInfo(name, "")
+        USERS[name].Posts = []Post{{Id: 1, Poster: name, Message: "by " + name, Stamp: now.Add(-time.Duration(len(USERS)) * time.Minute)}}
+    }
+    owner, viewer := USERS["alice"], USERS["viewer"]
+    owner.CreateList("people", false)
+    for _, name := range []string{"bob", "carol", "dave", "erin", "ghost"} {
+        owner.AddToList("people", name)
+    }
+    USERS["carol"].Private = true
+    USERS["dave"].Blocked["viewer"] = true
+    viewer.Muted["erin"] = true
+    USERS["bob"].Posts = append(USERS["bob"].Posts, Post{Id: 2, Poster: "bob", Stamp: now, Visibility: VisibilityFollowers})
+
+    timeline, ok := owner.ListChirps("people", viewer, USERS)
+    if !ok || timeline.Owner != "alice" || timeline.List.Name != "people" {
+        t.Fatalf("ListChirps = %+v, %v", timeline, ok)
+    }
+    if len(timeline.Posts) != 1 || timeline.Posts[0].Poster != "bob" || timeline.Posts[0].Id != 1 {
+        t.Errorf("timeline = %+v, want only bob's public post", timeline.Posts)
+    }
+
+    viewer.Follow(USERS["carol"])
+    viewer.Follow(USERS["bob"])
+    timeline, _ = owner.ListChirps("people", viewer, USERS)
+    var got []string
+    for _, post := range timeline.Posts {
+        got = append(got, post.Poster)
+    }
+    if !reflect.DeepEqual(got, []string{"bob", "bob", "carol"}) {
+        t.Errorf("timeline posters = %q, want bob's two posts then carol's, newest first", got)
+    }
+}
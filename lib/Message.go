@@ -0,0 +1,105 @@
+package lib
+
+import (
+    "sort"
+    "strings"
+    "time"
+)
+
+const MAX_CONVERSATION_MEMBERS = 8  // largest group conversation allowed, including the sender
+
+// Struct to hold a single direct message
+type Message struct {
+    Sender  string
+    Message string
+    Time    string
+    Stamp   time.Time
+    Tags    []string  // labels added by the filters
+}
+
+// Struct to hold a one-to-one or group conversation, every member keeps their own copy
+type Conversation struct {
+    Key      string
+    Members  []string
+    Messages []Message
+}
+
+// Struct to hold everything shown on a user's direct message inbox
+type Inbox struct {
+    FollowingOnly bool
+    Conversations []Conversation
+}
+
+// Builds the key identifying the conversation between the given members, the order of members does not matter
+func ConversationKey(members []string) string {
+    sorted := append([]string{}, members...)
+    sort.Strings(sorted)
+    return strings.Join(sorted, ",")
+}
+
+// Returns the members of the conversation other than the given user
+func (conv Conversation) Others(username string) []string {
+    var others []string
+    for _, member := range conv.Members {
+        if member != username {
+            others = append(others, member)
+        }
+    }
+    return others
+}
+
+// Returns the most recent message of the conversation
+func (conv Conversation) Last() Message {
+    if len(conv.Messages) == 0 {
+        return Message{}
+    }
+    return conv.Messages[len(conv.Messages)-1]
+}
+
+// Checks if the user accepts direct messages from the sender
+func (user *UserInfo) AcceptsMessagesFrom(sender string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return !user.FollowingOnlyMessages || user.Following[sender]
+}
+
+// Sets whether the user only accepts direct messages from users they follow
+func (user *UserInfo) SetMessagePrivacy(followingOnly bool) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    user.FollowingOnlyMessages = followingOnly
+}
+
+// Appends a message sent at the given time to the user's copy of the conversation between members,
+// creating the conversation if necessary
+func (user *UserInfo) AddMessage(members []string, sender, msg string, tags []string, now time.Time) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    key := ConversationKey(members)
+    conv, ok := user.Conversations[key]
+    if !ok {
+        conv = &Conversation{Key: key, Members: strings.Split(key, ",")}
+        user.Conversations[key] = conv
+    }
+    conv.Messages = append(conv.Messages, Message{
+        Sender:  sender,
+        Message: msg,
+        Time:    now.Format(time.RFC1123)[0:len(time.RFC1123)-4],
+        Stamp:   now,
+        Tags:    tags,
+    })
+}
+
+// Returns the user's inbox with a copy of all of their conversations, the most recently active first
+func (user *UserInfo) GetInbox() Inbox {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    result := []Conversation{}
+    for _, conv := range user.Conversations {
+        result = append(result, Conversation{conv.Key, conv.Members, append([]Message{}, conv.Messages...)})
+    }
+    sort.Slice(result, func(i, j int) bool {
+        return result[j].Last().Stamp.Before(result[i].Last().Stamp)
+    })
+    return Inbox{user.FollowingOnlyMessages, result}
+}
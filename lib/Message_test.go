@@ -0,0 +1,69 @@
+package lib
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestConversationKey(t *testing.T) {
+    tests := []struct {
+        members []string
+        want    string
+    }{
+        {[]string{"alice", "bob"}, "alice,bob"},
+        {[]string{"bob", "alice"}, "alice,bob"},
+        {[]string{"carol", "alice", "bob"}, "alice,bob,carol"},
+    }
+    for _, test := range tests {
+        if got := ConversationKey(test.members); got != test.want {
+            t.Errorf("ConversationKey(%q) = %q, want %q", test.members, got, test.want)
+        }
+    }
+}
+
+func TestAcceptsMessagesFrom(t *testing.T) {
+    user := NewUserInfo("alice", "")
+    user.Following["bob"] = true
+    if !user.AcceptsMessagesFrom("carol") {
+        t.Errorf("open inbox refused a message from carol")
+    }
+    user.SetMessagePrivacy(true)
+    if !user.AcceptsMessagesFrom("bob") {
+        t.Errorf("following-only inbox refused a message from a followed user")
+    }
+    if user.AcceptsMessagesFrom("carol") {
+        t.Errorf("following-only inbox accepted a message from a user that is not followed")
+    }
+}
+
+func TestInbox(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    user := NewUserInfo("alice", "")
+    user.AddMessage([]string{"alice", "bob"}, "bob", "hi", nil, now)
+    user.AddMessage([]string{"carol", "alice", "bob"}, "carol", "hello all", nil, now.Add(time.Minute))
+    user.AddMessage([]string{"bob", "alice"}, "alice", "hey bob", []string{"link"}, now.Add(2 * time.Minute))
+
+    inbox := user.GetInbox()
+    if len(inbox.Conversations) != 2 {
+        t.Fatalf("got %d conversations, want 2", len(inbox.Conversations))
+    }
+    first, second := inbox.Conversations[0], inbox.Conversations[1]
+    if first.Key != "alice,bob" || second.Key != "alice,bob,carol" {
+        t.Errorf("conversations = %q, %q, want the most recently active first", first.Key, second.Key)
+    }
+    if !reflect.DeepEqual(first.Others("alice"), []string{"bob"}) || !reflect.DeepEqual(second.Others("alice"), []string{"bob", "carol"}) {
+        t.Errorf("others = %q, %q", first.Others("alice"), second.Others("alice"))
+    }
+    if last := first.Last(); last.Sender != "alice" || last.Message != "hey bob" || !last.Stamp.Equal(now.Add(2 * time.Minute)) {
+        t.Errorf("last message = %+v", last)
+    }
+    if len(first.Messages) != 2 || first.Messages[0].Message != "hi" {
+        t.Errorf("messages = %+v, want hi then hey bob", first.Messages)
+    }
+
+    first.Messages[0].Message = "changed"
+    if user.Conversations["alice,bob"].Messages[0].Message != "hi" {
+        t.Errorf("changing the inbox changed the user's conversation")
+    }
+}
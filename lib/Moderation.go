@@ -0,0 +1,345 @@
+package lib
+
+import (
+    "sort"
+    "strings"
+    "time"
+    "unicode/utf8"
+)
+
+const MAX_REPORT_REASON_LENGTH = 200  // longest report reason or appeal in characters
+
+// Actions an admin can take on a reported post or account
+const (
+    ModerateDismiss = iota  // closes the open reports without acting on them
+    ModerateTakedown        // hides the post from everyone but its poster
+    ModerateRestore         // undoes a takedown, granting a pending appeal
+    ModerateSuspend         // hides the account and stops it from posting
+    ModerateReinstate       // undoes a suspension, granting a pending appeal
+    ModerateDenyAppeal      // keeps the takedown or suspension and closes the appeal
+    ModerateApprove         // publishes a post held for review by the filters
+)
+
+// States of the appeal against a takedown or suspension
+const (
+    AppealNone = iota
+    AppealPending
+    AppealDenied
+    AppealGranted
+)
+
+// Struct to hold a report filed against a post or an account
+type Report struct {
+    Id       int  // unique among the reports filed against the reported user
+    Reporter string
+    PostId   int  // the reported post, 0 when the account itself is reported
+    Reason   string
+    Time     string
+    Stamp    time.Time
+}
+
+// Struct to hold a takedown or suspension and the affected user's appeal against it
+type Sanction struct {
+    Active  bool
+    Reason  string
+    Appeal  int  // AppealNone, AppealPending, AppealDenied or AppealGranted
+    Message string  // the affected user's appeal
+}
+
+// Struct to hold an entry of the moderation queue, a reported or appealed post or account
+type ModerationItem struct {
+    Target   string
+    PostId   int     // 0 for the account itself
+    Message  string  // text of the post, empty for accounts or posts that no longer exist
+    HeldFor  string  // why the filters held the post for review, empty if it is not held
+    Reports  []Report  // open reports, oldest first
+    Sanction Sanction  // the current takedown or suspension with any appeal
+}
+
+// Struct to hold what a user can see about moderation of their own account
+type Standing struct {
+    Role       int
+    Suspension Sanction
+    TakenDown  []Post  // the user's posts removed by moderators, newest first
+}
+
+// Checks if the user may use the moderation queue
+func (standing Standing) CanModerate() bool {
+    return standing.Role >= RoleModerator
+}
+
+// Checks if the user may use the admin area
+func (standing Standing) CanAdminister() bool {
+    return standing.Role >= RoleAdmin
+}
+
+// Checks if the post has been taken down by moderators
+func (post Post) TakenDown() bool {
+    return post.Takedown.Active
+}
+
+// Checks if a report reason or appeal is not empty and not too long
+func ValidReason(reason string) bool {
+    return strings.TrimSpace(reason) != "" && utf8.RuneCountInString(reason) <= MAX_REPORT_REASON_LENGTH
+}
+
+// Files a report against the user or one of their posts
+// Returns false if the reporter already has an open report against the same post or account
+func (user *UserInfo) AddReport(reporter string, postId int, reason string, now time.Time) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    for _, report := range user.Reports {
+        if report.Reporter == reporter && report.PostId == postId {
+            return false
+        }
+    }
+    user.LastReportId++
+    stamp := now.Format(time.RFC1123)[0:len(time.RFC1123)-4]
+    user.Reports = append(user.Reports, Report{user.LastReportId, reporter, postId, reason, stamp, now})
+    return true
+}
+
+// Closes the open reports against the given post, or against the account itself when postId is 0,
+// returns the number of reports closed
+func (user *UserInfo) ResolveReports(postId int) int {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return user.resolveReports(postId)
+}
+
+// Closes reports without locking, the caller must hold the user's lock
+func (user *UserInfo) resolveReports(postId int) int {
+    kept := user.Reports[:0]
+    for _, report := range user.Reports {
+        if report.PostId == postId {
+            continue
+        }
+        kept = append(kept, report)
+    }
+    closed := len(user.Reports) - len(kept)
+    user.Reports = kept
+    return closed
+}
+
+// Checks if the user's account is suspended
+func (user *UserInfo) IsSuspended() bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return user.Suspension.Active
+}
+
+// Takes down the user's post with the given id, returns false if there is no such post
+func (user *UserInfo) TakeDown(id int, reason string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    for i := range user.Posts {
+        if user.Posts[i].Id == id {
+            user.Posts[i].Takedown = Sanction{Active: true, Reason: reason}
+            user.Posts[i].Held = false
+            user.resolveReports(id)
+            return true
+        }
+    }
+    return false
+}
+
+// Restores the user's taken down post with the given id, returns false if there is no such post
+// A pending appeal against the takedown is granted
+func (user *UserInfo) RestorePost(id int) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    for i := range user.Posts {
+        if user.Posts[i].Id == id && user.Posts[i].Takedown.Active {
+            user.Posts[i].Takedown = lift(user.Posts[i].Takedown)
+            return true
+        }
+    }
+    return false
+}
+
+// Publishes the user's post with the given id held for review, returns false if there is no such held post
+func (user *UserInfo) Approve(id int) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    for i := range user.Posts {
+        if user.Posts[i].Id == id && user.Posts[i].Held {
+            user.Posts[i].Held = false
+            return true
+        }
+    }
+    return false
+}
+
+// Suspends the user's account and closes every open report against it
+func (user *UserInfo) Suspend(reason string) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    user.Suspension = Sanction{Active: true, Reason: reason}
+    user.Reports = nil
+}
+
+// Lifts the user's suspension, returns false if the account is not suspended
+// A pending appeal against the suspension is granted
+func (user *UserInfo) Reinstate() bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    if !user.Suspension.Active {
+        return false
+    }
+    user.Suspension = lift(user.Suspension)
+    return true
+}
+
+// Returns the sanction lifted, granting its appeal if one was pending
+func lift(sanction Sanction) Sanction {
+    sanction.Active = false
+    if sanction.Appeal == AppealPending {
+        sanction.Appeal = AppealGranted
+    }
+    return sanction
+}
+
+// Returns the sanction against the given post, or against the account when id is 0
+// The caller must hold the user's lock
+func (user *UserInfo) sanction(id int) (*Sanction, bool) {
+    if id == 0 {
+        return &user.Suspension, true
+    }
+    for i := range user.Posts {
+        if user.Posts[i].Id == id {
+            return &user.Posts[i].Takedown, true
+        }
+    }
+    return nil, false
+}
+
+// Appeals the takedown of the given post, or the suspension of the account when id is 0
+// Each sanction can be appealed once, returns false if there is no active sanction or it was already appealed
+func (user *UserInfo) Appeal(id int, message string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    sanction, ok := user.sanction(id)
+    if !ok || !sanction.Active || sanction.Appeal != AppealNone {
+        return false
+    }
+    sanction.Appeal = AppealPending
+    sanction.Message = message
+    return true
+}
+
+// Denies the pending appeal against the given post, or against the suspension when id is 0
+// Returns false if there is no pending appeal
+func (user *UserInfo) DenyAppeal(id int) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    sanction, ok := user.sanction(id)
+    if !ok || sanction.Appeal != AppealPending {
+        return false
+    }
+    sanction.Appeal = AppealDenied
+    return true
+}
+
+// Returns the user's role, suspension and taken down posts
+func (user *UserInfo) GetStanding() Standing {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    standing := Standing{Role: user.Role, Suspension: user.Suspension}
+    for i := len(user.Posts) - 1; i >= 0; i-- {
+        if user.Posts[i].Takedown.Active {
+            standing.TakenDown = append(standing.TakenDown, user.Posts[i])
+        }
+    }
+    return standing
+}
+
+/*
+    Moderation queue collects every post and account with open reports or a pending appeal and every post
+    held for review by the filters.
+    Reports against the same post or account are grouped into one item. Items with a pending appeal come
+    first, then items by their oldest report, so whatever has waited longest is reviewed first.
+*/
+func ModerationQueue(USERS map[string]*UserInfo) []ModerationItem {
+    queue := []ModerationItem{}
+    for _, user := range USERS {
+        user.mut.Lock()
+        items := make(map[int]*ModerationItem)
+        var order []int
+        item := func(id int) *ModerationItem {
+            if found, ok := items[id]; ok {
+                return found
+            }
+            found := &ModerationItem{Target: user.Username, PostId: id}
+            if sanction, ok := user.sanction(id); ok {
+                found.Sanction = *sanction
+            }
+            for _, post := range user.Posts {
+                if post.Id == id && id != 0 {
+                    found.Message = post.Message
+                    if post.Held {
+                        found.HeldFor = post.HeldFor
+                    }
+                }
+            }
+            items[id] = found
+            order = append(order, id)
+            return found
+        }
+        for _, report := range user.Reports {
+            found := item(report.PostId)
+            found.Reports = append(found.Reports, report)
+        }
+        if user.Suspension.Appeal == AppealPending {
+            item(0)
+        }
+        for _, post := range user.Posts {
+            if post.Takedown.Appeal == AppealPending || post.Held {
+                item(post.Id)
+            }
+        }
+        for _, id := range order {
+            queue = append(queue, *items[id])
+        }
+        user.mut.Unlock()
+    }
+    sort.SliceStable(queue, func(i, j int) bool {
+        iAppeal := queue[i].Sanction.Pending()
+        jAppeal := queue[j].Sanction.Pending()
+        if iAppeal != jAppeal {
+            return iAppeal
+        }
+        return queue[i].oldest().Before(queue[j].oldest())
+    })
+    return queue
+}
+
+// Returns the time of the item's oldest open report, zero if it has none
+func (item ModerationItem) oldest() time.Time {
+    if len(item.Reports) == 0 {
+        return time.Time{}
+    }
+    return item.Reports[0].Stamp
+}
+
+// Checks if the sanction has an appeal waiting for review
+func (sanction Sanction) Pending() bool {
+    return sanction.Appeal == AppealPending
+}
+
+// Checks if the sanction is active and has not been appealed yet
+func (sanction Sanction) Appealable() bool {
+    return sanction.Active && sanction.Appeal == AppealNone
+}
+
+// Returns a short description of the appeal state shown to the affected user
+func (sanction Sanction) AppealText() string {
+    switch sanction.Appeal {
+        case AppealPending:
+            return "Appeal pending review"
+        case AppealDenied:
+            return "Appeal denied"
+        case AppealGranted:
+            return "Appeal granted"
+    }
+    return ""
+}
@@ -0,0 +1,102 @@
+package lib
+
+import (
+    "testing"
+    "time"
+)
+
+func TestReports(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    user := NewUserInfo("alice", "")
+    user.Posts = []Post{{Id: 1}, {Id: 2}}
+    if !user.AddReport("bob", 1, "spam", now) || user.AddReport("bob", 1, "again", now) {
+        t.Errorf("bob should report post 1 exactly once")
+    }
+    user.AddReport("carol", 1, "spam", now)
+    user.AddReport("bob", 0, "impersonation", now)
+    user.AddReport("bob", 2, "rude", now)
+    if len(user.Reports) != 4 || user.Reports[3].Id != 4 || !user.Reports[3].Stamp.Equal(now) {
+        t.Errorf("reports = %+v", user.Reports)
+    }
+    if closed := user.ResolveReports(1); closed != 2 || len(user.Reports) != 2 {
+        t.Errorf("ResolveReports(1) closed %d, left %d, want 2 and 2", closed, len(user.Reports))
+    }
+    if !user.TakeDown(2, "rude") || user.TakeDown(9, "missing") {
+        t.Errorf("only existing posts can be taken down")
+    }
+    if len(user.Reports) != 1 || user.Reports[0].PostId != 0 {
+        t.Errorf("taking down post 2 left reports %+v", user.Reports)
+    }
+    user.Suspend("impersonation")
+    if !user.IsSuspended() || len(user.Reports) != 0 {
+        t.Errorf("suspending did not close the account reports")
+    }
+}
+
+func TestSanctionsAndAppeals(t *testing.T) {
+    user := NewUserInfo("alice", "")
+    user.Posts = []Post{{Id: 1}, {Id: 2, Held: true}}
+
+    if user.Appeal(1, "not taken down") || user.RestorePost(1) {
+        t.Errorf("appealed or restored a post that is not taken down")
+    }
+    user.TakeDown(1, "spam")
+    if !user.Posts[0].TakenDown() || !user.Posts[0].Takedown.Appealable() {
+        t.Errorf("taken down post = %+v", user.Posts[0].Takedown)
+    }
+    if !user.Appeal(1, "it was a joke") || user.Appeal(1, "again") {
+        t.Errorf("the takedown should be appealed exactly once")
+    }
+    if !user.Posts[0].Takedown.Pending() || user.Posts[0].Takedown.AppealText() != "Appeal pending review" {
+        t.Errorf("appeal = %+v", user.Posts[0].Takedown)
+    }
+    if !user.RestorePost(1) || user.Posts[0].TakenDown() || user.Posts[0].Takedown.AppealText() != "Appeal granted" {
+        t.Errorf("restoring did not grant the appeal: %+v", user.Posts[0].Takedown)
+    }
+
+    user.Suspend("spam")
+    user.Appeal(0, "please")
+    if !user.DenyAppeal(0) || user.DenyAppeal(0) || !user.IsSuspended() || user.Suspension.AppealText() != "Appeal denied" {
+        t.Errorf("denied appeal = %+v", user.Suspension)
+    }
+    if user.Appeal(0, "please again") {
+        t.Errorf("a denied suspension was appealed twice")
+    }
+    if !user.Reinstate() || user.Reinstate() || user.IsSuspended() {
+        t.Errorf("the account should be reinstated exactly once")
+    }
+
+    if !user.Approve(2) || user.Approve(2) || user.Posts[1].Held {
+        t.Errorf("the held post should be approved exactly once")
+    }
+}
+
+func TestModerationQueue(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    alice, bob, carol := NewUserInfo("alice", ""), NewUserInfo("bob", ""), NewUserInfo("carol", "")
+    alice.Posts = []Post{{Id: 1, Message: "buy now"}}
+    alice.AddReport("bob", 1, "spam", now.Add(time.Minute))
+    alice.AddReport("carol", 1, "spam", now.Add(2 * time.Minute))
+    bob.AddReport("alice", 0, "rude", now)
+    carol.Posts = []Post{{Id: 1, Message: "link", Held: true, HeldFor: "links"}, {Id: 2, Message: "appealed"}}
+    carol.TakeDown(2, "spam")
+    carol.Appeal(2, "sorry")
+    USERS := map[string]*UserInfo{"alice": alice, "bob": bob, "carol": carol}
+
+    queue := ModerationQueue(USERS)
+    if len(queue) != 4 {
+        t.Fatalf("queue holds %d items, want 4: %+v", len(queue), queue)
+    }
+    want := []struct {
+        target string
+        id     int
+    }{{"carol", 2}, {"carol", 1}, {"bob", 0}, {"alice", 1}}
+    for i, item := range queue {
+        if item.Target != want[i].target || item.PostId != want[i].id {
+            t.Errorf("item %d = %s/%d, want %s/%d", i, item.Target, item.PostId, want[i].target, want[i].id)
+        }
+    }
+    if len(queue[3].Reports) != 2 || queue[3].Message != "buy now" || queue[1].HeldFor != "links" {
+        t.Errorf("queue items = %+v", queue)
+    }
+}
@@ -0,0 +1,120 @@
+package lib
+
+import (
+    "sort"
+)
+
+// Constants of the arithmetic mapping between Hangul syllables and their jamo
+const (
+    hangulBase  = 0xAC00
+    jamoLBase   = 0x1100
+    jamoVBase   = 0x1161
+    jamoTBase   = 0x11A7
+    jamoLCount  = 19
+    jamoVCount  = 21
+    jamoTCount  = 28
+    jamoNCount  = jamoVCount * jamoTCount
+    hangulCount = jamoLCount * jamoNCount
+)
+
+/*
+    NFC puts the text in Unicode normalization form C: every character is fully decomposed, runs of
+    combining marks are put in canonical order and the result is composed again wherever a composite
+    character exists, so composed and decomposed spellings of the same text become identical.
+    Text without combining marks or characters that decompose, such as plain ASCII, is returned as is.
+*/
+func NFC(text string) string {
+    plain := true
+    for _, r := range text {
+        if r >= 0x300 {
+            plain = false
+            break
+        }
+    }
+    if plain {
+        return text
+    }
+    return string(compose(decompose([]rune(text))))
+}
+
+// Fully decomposes the characters and puts every run of combining marks in canonical order
+func decompose(runes []rune) []rune {
+    var result []rune
+    for _, r := range runes {
+        result = appendDecomposed(result, r)
+    }
+    for start := 0; start < len(result); {
+        if combiningClass[result[start]] == 0 {
+            start++
+            continue
+        }
+        end := start
+        for end < len(result) && combiningClass[result[end]] != 0 {
+            end++
+        }
+        marks := result[start:end]
+        sort.SliceStable(marks, func(i, j int) bool {
+            return combiningClass[marks[i]] < combiningClass[marks[j]]
+        })
+        start = end
+    }
+    return result
+}
+
+// Appends the full canonical decomposition of the character
+func appendDecomposed(result []rune, r rune) []rune {
+    if index := r - hangulBase; index >= 0 && index < hangulCount {
+        result = append(result, jamoLBase + index / jamoNCount, jamoVBase + index % jamoNCount / jamoTCount)
+        if t := index % jamoTCount; t != 0 {
+            result = append(result, jamoTBase + t)
+        }
+        return result
+    }
+    parts, ok := decompositions[r]
+    if !ok {
+        return append(result, r)
+    }
+    for _, part := range parts {
+        result = appendDecomposed(result, part)
+    }
+    return result
+}
+
+// Composes decomposed characters in canonical order, each mark joins the last starter unless it is blocked
+// by a mark of the same or a higher combining class, or by another starter, in between
+func compose(runes []rune) []rune {
+    result := runes[:0]
+    starter := -1
+    var last uint8
+    for _, r := range runes {
+        class := combiningClass[r]
+        if starter >= 0 && (starter == len(result) - 1 || last != 0 && last < class) {
+            if composite, ok := composePair(result[starter], r); ok {
+                result[starter] = composite
+                continue
+            }
+        }
+        if class == 0 {
+            starter = len(result)
+        }
+        last = class
+        result = append(result, r)
+    }
+    return result
+}
+
+// Returns the primary composite of the two characters, if there is one
+func composePair(first, second rune) (rune, bool) {
+    if l := first - jamoLBase; l >= 0 && l < jamoLCount {
+        if v := second - jamoVBase; v >= 0 && v < jamoVCount {
+            return hangulBase + (l * jamoVCount + v) * jamoTCount, true
+        }
+    }
+    if index := first - hangulBase; index >= 0 && index < hangulCount && index % jamoTCount == 0 {
+        if t := second - jamoTBase; t > 0 && t < jamoTCount {
+            return first + t, true
+        }
+    }
+    composite, ok := compositions[[2]rune{first, second}]
+    return composite, ok
+}
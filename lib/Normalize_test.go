@@ -0,0 +1,31 @@
+package lib
+
+import (
+    "testing"
+)
+
+func TestNFC(t *testing.T) {
+    tests := []struct {
+        name, text, want string
+    }{
+        {"empty", "", ""},
+        {"ascii", "hello, world", "hello, world"},
+        {"composed", "caf\u00e9", "caf\u00e9"},
+        {"decomposed", "cafe\u0301", "caf\u00e9"},
+        {"two marks", "a\u0323\u0302", "\u1ead"},
+        {"marks out of order", "a\u0302\u0323", "\u1ead"},
+        {"mark that does not compose", "q\u0301", "q\u0301"},
+        {"blocked by a mark of the same class", "a\u0308\u0301", "\u00e4\u0301"},
+        {"singleton", "\u212b", "\u00c5"},
+        {"composition exclusion", "\u0958", "\u0915\u093c"},
+        {"hangul syllable", "\uac01", "\uac01"},
+        {"hangul jamo", "\u1100\u1161\u11a8", "\uac01"},
+        {"hangul jamo without final", "\u1100\u1161", "\uac00"},
+        {"leading mark", "\u0301e", "\u0301e"},
+    }
+    for _, test := range tests {
+        if got := NFC(test.text); got != test.want {
+            t.Errorf("%s: NFC(%+q) = %+q, want %+q", test.name, test.text, got, test.want)
+        }
+    }
+}
@@ -0,0 +1,90 @@
+package lib
+
+import (
+    "regexp"
+    "time"
+)
+
+// Kinds of events that are recorded in a user's notification inbox
+const (
+    NotifyMention = iota
+    NotifyFollow
+    NotifyFollowRequest
+    NotifyFollowAccepted
+    NotifyModeration  // a takedown, suspension or appeal decision, the message holds the whole text
+)
+
+var mentionPattern = regexp.MustCompile(`@(\w+)`)  // matches @username inside a chirp
+
+// Struct to hold a single entry of a user's notification inbox
+type Notification struct {
+    Kind    int
+    From    string
+    Message string
+    Time    string
+    Stamp   time.Time
+    Read    bool
+}
+
+// Builds the line of text shown for the notification in the inbox
+func (note Notification) Text() string {
+    switch note.Kind {
+        case NotifyMention:
+            return note.From + " mentioned you: " + note.Message
+        case NotifyFollow:
+            return note.From + " followed you"
+        case NotifyFollowRequest:
+            return note.From + " asked to follow you"
+        case NotifyFollowAccepted:
+            return note.From + " accepted your follow request"
+    }
+    return note.Message
+}
+
+// Finds every @username in the message that belongs to an existing user, each user is listed once
+func ParseMentions(msg string, USERS map[string]*UserInfo) []string {
+    var mentions []string
+    seen := make(map[string]bool)
+    for _, match := range mentionPattern.FindAllStringSubmatch(msg, -1) {
+        name := match[1]
+        if _, ok := USERS[name]; !ok || seen[name] {
+            continue
+        }
+        seen[name] = true
+        mentions = append(mentions, name)
+    }
+    return mentions
+}
+
+// Adds a new unread notification sent at the given time to the user's inbox
+func (user *UserInfo) Notify(kind int, from, msg string, now time.Time) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    user.Notifications = append(user.Notifications, Notification{
+        Kind:    kind,
+        From:    from,
+        Message: msg,
+        Time:    now.Format(time.RFC1123)[0:len(time.RFC1123)-4],
+        Stamp:   now,
+    })
+}
+
+// Returns a copy of the user's notifications with the newest first
+func (user *UserInfo) GetNotifications() []Notification {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    result := []Notification{}
+    for i := len(user.Notifications) - 1; i >= 0; i-- {
+        result = append(result, user.Notifications[i])
+    }
+    return result
+}
+
+// Marks every notification in the user's inbox as read
+func (user *UserInfo) ReadNotifications() {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    for i := range user.Notifications {
+        user.Notifications[i].Read = true
+    }
+}
@@ -0,0 +1,115 @@
+package lib
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestParseMentions(t *testing.T) {
+    USERS := map[string]*UserInfo{
+        "bob":   NewUserInfo("bob", ""),
+        "carol": NewUserInfo("carol", ""),
+    }
+    tests := []struct {
+        msg  string
+        want []string
+    }{
+        {"no mentions here", nil},
+        {"hello @bob", []string{"bob"}},
+        {"@carol and @bob", []string{"carol", "bob"}},
+        {"@bob, @bob again", []string{"bob"}},
+        {"@ghost is not a user", nil},
+        {"@bobby is not bob", nil},
+        {"(@carol)!", []string{"carol"}},
+    }
+    for _, test := range tests {
+        if got := ParseMentions(test.msg, USERS); !reflect.DeepEqual(got, test.want) {
+            t.Errorf("ParseMentions(%q) = %q, want %q", test.msg, got, test.want)
+        }
+    }
+}
+
+func TestWritePostMentions(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    tests := []struct {
+        name         string
+        visibility   int
+        wantMentions []string
+        wantNotified []string
+    }{
+        {"public", VisibilityPublic, []string{"alice", "bob", "dave"}, []string{"bob", "dave"}},
+        {"followers only", VisibilityFollowers, []string{"alice", "bob", "dave"}, []string{"bob"}},
+        {"mentioned only", VisibilityMentioned, []string{"alice", "bob", "dave"}, []string{"bob", "dave"}},
+    }
+    for _, test := range tests {
+        USERS := map[string]*UserInfo{}
+        for _, name := range []string{"alice", "bob", "carol", "dave", "erin"} {
+            USERS[name] = NewUserInfo(name, "")
+        }
+        USERS["bob"].Follow(USERS["alice"])
+        USERS["carol"].Block(USERS["alice"])
+        USERS["alice"].Block(USERS["erin"])
+
+        draft := Post{Message: "@alice @bob @carol @dave @erin @ghost", Stamp: now, Visibility: test.visibility}
+        post, notified := USERS["alice"].WritePost(draft, USERS)
+        if !reflect.DeepEqual(post.Mentions, test.wantMentions) {
+            t.Errorf("%s: mentions = %q, want %q", test.name, post.Mentions, test.wantMentions)
+        }
+        if !reflect.DeepEqual(notified, test.wantNotified) {
+            t.Errorf("%s: notified = %q, want %q", test.name, notified, test.wantNotified)
+        }
+        for name, user := range USERS {
+            notes := user.GetNotifications()
+            if !contains(test.wantNotified, name) {
+                if len(notes) != 0 {
+                    t.Errorf("%s: %s got notifications %+v", test.name, name, notes)
+                }
+                continue
+            }
+            if len(notes) != 1 || notes[0].Kind != NotifyMention || notes[0].From != "alice" || !notes[0].Stamp.Equal(now) {
+                t.Errorf("%s: %s notifications = %+v, want one mention by alice", test.name, name, notes)
+            }
+        }
+        if post.Id != 1 || post.Poster != "alice" || !post.Stamp.Equal(now) {
+            t.Errorf("%s: post = %+v", test.name, post)
+        }
+    }
+}
+
+func TestNotifications(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    user := NewUserInfo("alice", "")
+    user.Notify(NotifyFollow, "bob", "", now)
+    user.Notify(NotifyMention, "carol", "hi @alice", now.Add(time.Minute))
+    user.Notify(NotifyModeration, "", "Your chirp was taken down", now.Add(2 * time.Minute))
+
+    var got []string
+    for _, note := range user.GetNotifications() {
+        if note.Read {
+            t.Errorf("%q is read before ReadNotifications", note.Text())
+        }
+        got = append(got, note.Text())
+    }
+    want := []string{"Your chirp was taken down", "carol mentioned you: hi @alice", "bob followed you"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("notifications = %q, want newest first %q", got, want)
+    }
+
+    user.ReadNotifications()
+    for _, note := range user.GetNotifications() {
+        if !note.Read {
+            t.Errorf("%q is unread after ReadNotifications", note.Text())
+        }
+    }
+}
+
+// Checks if the name is in the slice
+func contains(names []string, name string) bool {
+    for _, other := range names {
+        if other == name {
+            return true
+        }
+    }
+    return false
+}
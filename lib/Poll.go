@@ -0,0 +1,138 @@
+package lib
+
+import (
+    "strings"
+    "time"
+    "unicode/utf8"
+)
+
+const MIN_POLL_OPTIONS = 2
+const MAX_POLL_OPTIONS = 4
+const MAX_POLL_OPTION_LENGTH = 25              // longest poll option in characters
+const MIN_POLL_DURATION = 5 * time.Minute
+const MAX_POLL_DURATION = 7 * 24 * time.Hour
+
+// Struct to hold a poll attached to a post, a post has a poll if it has options
+type Poll struct {
+    Options  []string
+    Counts   []int  // votes for each option, replaced rather than changed so copies of the post are never modified
+    ClosesAt time.Time
+    Closed   bool   // the counts are final, set by the master once ClosesAt has passed
+}
+
+// Creates a poll with the given options closing after the duration
+// Returns false if there are too few or too many options, an option is empty or too long, or the duration
+// is outside the allowed range
+func NewPoll(options []string, duration time.Duration, now time.Time) (Poll, bool) {
+    if len(options) < MIN_POLL_OPTIONS || len(options) > MAX_POLL_OPTIONS {
+        return Poll{}, false
+    }
+    if duration < MIN_POLL_DURATION || duration > MAX_POLL_DURATION {
+        return Poll{}, false
+    }
+    for _, option := range options {
+        if strings.TrimSpace(option) == "" || utf8.RuneCountInString(option) > MAX_POLL_OPTION_LENGTH {
+            return Poll{}, false
+        }
+    }
+    return Poll{options, make([]int, len(options)), now.Add(duration), false}, true
+}
+
+// Checks if the poll has options, posts without a poll have an empty one
+func (poll Poll) Exists() bool {
+    return len(poll.Options) > 0
+}
+
+// Returns the number of votes cast in the poll
+func (poll Poll) Total() int {
+    total := 0
+    for _, count := range poll.Counts {
+        total += count
+    }
+    return total
+}
+
+// Struct to hold an option and its count for display
+type PollResult struct {
+    Index   int
+    Option  string
+    Count   int
+    Percent int
+}
+
+// Returns each option with its count and share of the votes, in option order
+func (poll Poll) Results() []PollResult {
+    total := poll.Total()
+    results := make([]PollResult, len(poll.Options))
+    for i, option := range poll.Options {
+        results[i] = PollResult{Index: i, Option: option, Count: poll.Counts[i]}
+        if total > 0 {
+            results[i].Percent = poll.Counts[i] * 100 / total
+        }
+    }
+    return results
+}
+
+// Records the voter's vote for an option in the poll on the user's post with the given id
+// Returns StatusAccepted, or the status explaining why the vote was not counted
+func (user *UserInfo) Vote(id int, voter string, option int, now time.Time) int {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    for i := range user.Posts {
+        post := &user.Posts[i]
+        if post.Id != id || post.Expired(now) {
+            continue
+        }
+        if !post.Poll.Exists() {
+            return StatusPostNotFound
+        }
+        if post.Poll.Closed || !now.Before(post.Poll.ClosesAt) {
+            return StatusPollClosed
+        }
+        if option < 0 || option >= len(post.Poll.Options) {
+            return StatusInvalidPoll
+        }
+        if user.PollVoters[id][voter] {
+            return StatusAlreadyVoted
+        }
+        if user.PollVoters[id] == nil {
+            user.PollVoters[id] = make(map[string]bool)
+        }
+        user.PollVoters[id][voter] = true
+        counts := append([]int{}, post.Poll.Counts...)
+        counts[option]++
+        post.Poll.Counts = counts
+        return StatusAccepted
+    }
+    return StatusPostNotFound
+}
+
+// Returns the ids and current counts of the user's open polls whose closing time is not after now
+func (user *UserInfo) DuePolls(now time.Time) map[int][]int {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    due := make(map[int][]int)
+    for _, post := range user.Posts {
+        if post.Poll.Exists() && !post.Poll.Closed && !post.Poll.ClosesAt.After(now) {
+            due[post.Id] = append([]int{}, post.Poll.Counts...)
+        }
+    }
+    return due
+}
+
+// Closes the poll on the user's post with the given id, replacing its counts with the final counts
+// Returns false if there is no such open poll
+func (user *UserInfo) ClosePoll(id int, counts []int) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    for i := range user.Posts {
+        post := &user.Posts[i]
+        if post.Id == id && post.Poll.Exists() && !post.Poll.Closed && len(counts) == len(post.Poll.Options) {
+            post.Poll.Counts = append([]int{}, counts...)
+            post.Poll.Closed = true
+            delete(user.PollVoters, id)
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,123 @@
+package lib
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestNewPoll(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    tests := []struct {
+        name     string
+        options  []string
+        duration time.Duration
+        want     bool
+    }{
+        {"two options", []string{"tea", "coffee"}, time.Hour, true},
+        {"four options", []string{"a", "b", "c", "d"}, MAX_POLL_DURATION, true},
+        {"one option", []string{"tea"}, time.Hour, false},
+        {"five options", []string{"a", "b", "c", "d", "e"}, time.Hour, false},
+        {"empty option", []string{"tea", "  "}, time.Hour, false},
+        {"long option", []string{"tea", strings.Repeat("a", MAX_POLL_OPTION_LENGTH + 1)}, time.Hour, false},
+        {"too short", []string{"tea", "coffee"}, MIN_POLL_DURATION - time.Second, false},
+        {"too long", []string{"tea", "coffee"}, MAX_POLL_DURATION + time.Second, false},
+    }
+    for _, test := range tests {
+        poll, ok := NewPoll(test.options, test.duration, now)
+        if ok != test.want {
+            t.Errorf("%s: NewPoll ok = %v, want %v", test.name, ok, test.want)
+            continue
+        }
+        if ok && (!poll.ClosesAt.Equal(now.Add(test.duration)) || len(poll.Counts) != len(test.options) || poll.Closed) {
+            t.Errorf("%s: poll = %+v", test.name, poll)
+        }
+    }
+}
+
+func TestPollResults(t *testing.T) {
+    poll := Poll{Options: []string{"tea", "coffee", "water"}, Counts: []int{1, 2, 0}}
+    want := []PollResult{{0, "tea", 1, 33}, {1, "coffee", 2, 66}, {2, "water", 0, 0}}
+    if poll.Total() != 3 || !reflect.DeepEqual(poll.Results(), want) {
+        t.Errorf("total %d, results %+v, want 3 and %+v", poll.Total(), poll.Results(), want)
+    }
+    if (Poll{}).Exists() {
+        t.Errorf("an empty poll exists")
+    }
+}
+
+func TestVote(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    poll, _ := NewPoll([]string{"tea", "coffee"}, time.Hour, now)
+    user := NewUserInfo("alice", "")
+    user.Posts = []Post{
+        {Id: 1, Poll: poll},
+        {Id: 2},
+        {Id: 3, Poll: poll, ExpiresAt: now.Add(time.Minute)},
+    }
+    before := user.Posts[0]
+
+    tests := []struct {
+        name   string
+        id     int
+        voter  string
+        option int
+        at     time.Time
+        want   int
+    }{
+        {"vote", 1, "bob", 1, now, StatusAccepted},
+        {"second vote", 1, "bob", 0, now, StatusAlreadyVoted},
+        {"other voter", 1, "carol", 1, now.Add(59 * time.Minute), StatusAccepted},
+        {"bad option", 1, "dave", 2, now, StatusInvalidPoll},
+        {"negative option", 1, "dave", -1, now, StatusInvalidPoll},
+        {"at closing time", 1, "dave", 0, now.Add(time.Hour), StatusPollClosed},
+        {"no poll", 2, "dave", 0, now, StatusPostNotFound},
+        {"no post", 9, "dave", 0, now, StatusPostNotFound},
+        {"expired post", 3, "dave", 0, now.Add(2 * time.Minute), StatusPostNotFound},
+    }
+    for _, test := range tests {
+        if got := user.Vote(test.id, test.voter, test.option, test.at); got != test.want {
+            t.Errorf("%s: Vote = %d, want %d", test.name, got, test.want)
+        }
+    }
+    if !reflect.DeepEqual(user.Posts[0].Poll.Counts, []int{0, 2}) {
+        t.Errorf("counts = %v, want [0 2]", user.Posts[0].Poll.Counts)
+    }
+    if !reflect.DeepEqual(before.Poll.Counts, []int{0, 0}) {
+        t.Errorf("voting changed a copy of the post taken before: %v", before.Poll.Counts)
+    }
+}
+
+func TestCloseDuePolls(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    short, _ := NewPoll([]string{"tea", "coffee"}, MIN_POLL_DURATION, now)
+    long, _ := NewPoll([]string{"yes", "no"}, time.Hour, now)
+    user := NewUserInfo("alice", "")
+    user.Posts = []Post{{Id: 1, Poll: short}, {Id: 2, Poll: long}, {Id: 3}}
+    user.Vote(1, "bob", 0, now)
+
+    if due := user.DuePolls(now); len(due) != 0 {
+        t.Errorf("polls due before closing: %v", due)
+    }
+    due := user.DuePolls(now.Add(MIN_POLL_DURATION))
+    if !reflect.DeepEqual(due, map[int][]int{1: {1, 0}}) {
+        t.Fatalf("due polls = %v, want poll 1 with counts [1 0]", due)
+    }
+
+    if user.ClosePoll(1, []int{1}) {
+        t.Errorf("closed a poll with the wrong number of counts")
+    }
+    if !user.ClosePoll(1, due[1]) || user.ClosePoll(1, due[1]) || user.ClosePoll(3, nil) {
+        t.Errorf("the poll should close exactly once")
+    }
+    if !user.Posts[0].Poll.Closed || user.PollVoters[1] != nil {
+        t.Errorf("closed poll = %+v, voters %v", user.Posts[0].Poll, user.PollVoters[1])
+    }
+    if got := user.Vote(1, "carol", 1, now); got != StatusPollClosed {
+        t.Errorf("Vote on a closed poll = %d, want StatusPollClosed", got)
+    }
+    if due := user.DuePolls(now.Add(2 * time.Hour)); !reflect.DeepEqual(due, map[int][]int{2: {0, 0}}) {
+        t.Errorf("due polls after closing = %v, want only poll 2", due)
+    }
+}
@@ -0,0 +1,77 @@
+package lib
+
+import "time"
+
+const MAX_DISPLAY_NAME_LENGTH = 50  // longest display name in characters
+const MAX_BIO_LENGTH = 160          // longest bio in characters
+
+// Struct to hold everything shown on a user's public profile page
+type Profile struct {
+    User        UserResult  // counts and whether the viewer follows the user
+    DisplayName string
+    Bio         string
+    Joined      string
+    Hidden      bool    // the account is private and the viewer does not follow it, no posts are included
+    Suspended   bool    // the account is suspended by moderators, no posts are included for other users
+    Pinned      *Post   // the post featured at the top of the profile, nil if none or hidden
+    Posts       []Post  // the requested page of the user's own posts, newest first
+    Page        int     // pages start at 1
+    HasMore     bool
+    Retention   time.Duration  // how long the user's posts are kept, only filled in on the user's own profile
+}
+
+// Updates the display name and bio of the user
+func (user *UserInfo) EditProfile(displayName, bio string) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    user.DisplayName = displayName
+    user.Bio = bio
+}
+
+// Fills in the profile fields stored on the user and the given page of their posts
+// No posts are filled in if the profile is hidden from the viewer, expired posts and posts hidden from
+// the viewer by their visibility are left out before the page is cut
+// The pinned post is only filled in on the first page
+func (user *UserInfo) FillProfile(profile *Profile, viewer *UserInfo, page int) {
+    follows := user != viewer && viewer.IsFollowing(user)
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    profile.DisplayName = user.DisplayName
+    profile.Bio = user.Bio
+    if !user.Joined.IsZero() {
+        profile.Joined = user.Joined.Format("January 2006")
+    }
+
+    if page < 1 {
+        page = 1
+    }
+    profile.Page = page
+    profile.Posts = []Post{}
+    if profile.Hidden {
+        return
+    }
+    now := time.Now()
+    for _, post := range user.Posts {
+        if page == 1 && post.Id == user.Pinned && !post.Expired(now) && post.VisibleTo(viewer.Username, follows) {
+            pinned := post
+            profile.Pinned = &pinned
+        }
+    }
+    // pages are cut from the posts the viewer can see, so hidden posts never leave a page short
+    var visible []Post
+    for i := len(user.Posts) - 1; i >= 0; i-- {
+        if !user.Posts[i].Expired(now) && user.Posts[i].VisibleTo(viewer.Username, follows) {
+            visible = append(visible, user.Posts[i])
+        }
+    }
+    start := (page - 1) * PAGE_SIZE
+    if start > len(visible) {
+        start = len(visible)
+    }
+    end := start + PAGE_SIZE
+    if end > len(visible) {
+        end = len(visible)
+    }
+    profile.Posts = append(profile.Posts, visible[start:end]...)
+    profile.HasMore = end < len(visible)
+}
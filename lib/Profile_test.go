@@ -0,0 +1,65 @@
+package lib
+
+import (
+    "testing"
+    "time"
+)
+
+// Builds a user with PAGE_SIZE public posts interleaved with followers-only posts, and one expired post
+func profileUser() *UserInfo {
+    user := NewUserInfo("alice", "")
+    user.Joined = time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+    user.EditProfile("Alice", "Tea and Go")
+    for i := 1; i <= 2 * PAGE_SIZE; i++ {
+        post := Post{Id: i, Poster: "alice"}
+        if i % 2 == 0 {
+            post.Visibility = VisibilityFollowers
+        }
+        user.Posts = append(user.Posts, post)
+    }
+    user.Posts = append(user.Posts, Post{Id: 2 * PAGE_SIZE + 1, Poster: "alice", ExpiresAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)})
+    user.Pinned = 1
+    return user
+}
+
+func TestFillProfile(t *testing.T) {
+    user, stranger, follower := profileUser(), NewUserInfo("bob", ""), NewUserInfo("carol", "")
+    follower.Follow(user)
+    tests := []struct {
+        name      string
+        viewer    *UserInfo
+        page      int
+        wantFirst int
+        wantLen   int
+        wantMore  bool
+        wantPin   bool
+    }{
+        {"stranger first page", stranger, 1, 2 * PAGE_SIZE - 1, PAGE_SIZE, false, true},
+        {"stranger second page", stranger, 2, 0, 0, false, false},
+        {"follower first page", follower, 1, 2 * PAGE_SIZE, PAGE_SIZE, true, true},
+        {"follower second page", follower, 2, PAGE_SIZE, PAGE_SIZE, false, false},
+        {"owner page zero", user, 0, 2 * PAGE_SIZE, PAGE_SIZE, true, true},
+    }
+    for _, test := range tests {
+        var profile Profile
+        user.FillProfile(&profile, test.viewer, test.page)
+        if len(profile.Posts) != test.wantLen || profile.HasMore != test.wantMore {
+            t.Errorf("%s: got %d posts, more %v, want %d, more %v", test.name, len(profile.Posts), profile.HasMore, test.wantLen, test.wantMore)
+        }
+        if test.wantLen > 0 && len(profile.Posts) > 0 && profile.Posts[0].Id != test.wantFirst {
+            t.Errorf("%s: first post %d, want %d", test.name, profile.Posts[0].Id, test.wantFirst)
+        }
+        if (profile.Pinned != nil) != test.wantPin {
+            t.Errorf("%s: pinned = %v, want pinned %v", test.name, profile.Pinned, test.wantPin)
+        }
+        if profile.DisplayName != "Alice" || profile.Bio != "Tea and Go" || profile.Joined != "March 2026" {
+            t.Errorf("%s: profile fields = %q, %q, %q", test.name, profile.DisplayName, profile.Bio, profile.Joined)
+        }
+    }
+
+    profile := Profile{Hidden: true}
+    user.FillProfile(&profile, stranger, 1)
+    if len(profile.Posts) != 0 || profile.Pinned != nil {
+        t.Errorf("hidden profile shows %d posts and pinned %v", len(profile.Posts), profile.Pinned)
+    }
+}
@@ -0,0 +1,114 @@
+package lib
+
+import (
+    "math"
+    "sort"
+    "strconv"
+    "time"
+)
+
+const MAX_RECOMMENDATIONS = 5  // most accounts suggested at once
+const HASHTAG_HISTORY = 50     // number of recent posts whose hashtags describe a user's interests
+
+// Struct to hold a suggested account and why it was suggested
+type Recommendation struct {
+    User   UserResult
+    Reason string
+}
+
+/*
+    Returns the set of hashtags used in those of the user's most recent posts the viewer can see.
+    Expired, taken down and held posts never count, so a suggestion's reason cannot reveal a hashtag the
+    viewer has no other way of seeing.
+*/
+func (user *UserInfo) RecentHashtags(viewer *UserInfo, now time.Time) map[string]bool {
+    user.mut.Lock()
+    start := len(user.Posts) - HASHTAG_HISTORY
+    if start < 0 {
+        start = 0
+    }
+    recent := append([]Post{}, user.Posts[start:]...)
+    user.mut.Unlock()
+
+    tags := make(map[string]bool)
+    for _, post := range recent {
+        if post.Expired(now) || post.TakenDown() || post.Held || !user.CanSeePost(viewer, post) {
+            continue
+        }
+        for _, tag := range Hashtags(post.Message) {
+            tags[tag] = true
+        }
+    }
+    return tags
+}
+
+// Ranks accounts the user does not follow yet using the follow graph and hashtags
+// Accounts followed by the people the user follows count the most, then accounts chirping about the same
+// hashtags as the user, then overall popularity
+// Blocked accounts and accounts with a pending follow request are never suggested
+// Only hashtags of posts the user can see are compared
+func (user *UserInfo) Recommend(USERS map[string]*UserInfo, now time.Time) []Recommendation {
+    following := user.GetFollowing()
+    followed := map[string]bool{user.Username: true}
+    for _, name := range following {
+        followed[name] = true
+    }
+
+    friendsOfFriends := make(map[string]int)
+    for _, name := range following {
+        if friend, ok := USERS[name]; ok {
+            for _, candidate := range friend.GetFollowing() {
+                if !followed[candidate] {
+                    friendsOfFriends[candidate]++
+                }
+            }
+        }
+    }
+    interests := user.RecentHashtags(user, now)
+
+    type candidate struct {
+        name   string
+        score  float64
+        reason string
+    }
+    var candidates []candidate
+    for name, other := range USERS {
+        if followed[name] || EitherBlocked(user, other) || user.HasRequested(other) || other.IsHidden() {
+            continue
+        }
+        var shared []string
+        for tag := range other.RecentHashtags(user, now) {
+            if interests[tag] {
+                shared = append(shared, tag)
+            }
+        }
+        sort.Strings(shared)
+        followers := other.FollowerCount()
+        score := 3 * float64(friendsOfFriends[name]) + 2 * float64(len(shared)) + math.Log2(1 + float64(followers))
+        if score == 0 {
+            continue
+        }
+
+        reason := strconv.Itoa(followers) + " followers"
+        if friendsOfFriends[name] == 1 {
+            reason = "Followed by someone you follow"
+        } else if friendsOfFriends[name] > 1 {
+            reason = "Followed by " + strconv.Itoa(friendsOfFriends[name]) + " people you follow"
+        } else if len(shared) > 0 {
+            reason = "Also chirps about #" + shared[0]
+        }
+        candidates = append(candidates, candidate{name, score, reason})
+    }
+    sort.Slice(candidates, func(i, j int) bool {
+        if candidates[i].score == candidates[j].score {
+            return candidates[i].name < candidates[j].name
+        }
+        return candidates[i].score > candidates[j].score
+    })
+
+    result := []Recommendation{}
+    for i := 0; i < len(candidates) && i < MAX_RECOMMENDATIONS; i++ {
+        result = append(result, Recommendation{User: UserResult{Username: candidates[i].name}, Reason: candidates[i].reason})
+    }
+    return result
+}
@@ -0,0 +1,55 @@
+package lib
+
+import (
+    "testing"
+    "time"
+)
+
+func TestRecommendOnlySharesVisibleHashtags(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    post := func(poster, message string) Post {
+        return Post{Id: 1, Poster: poster, Message: message, Stamp: now.Add(-time.Hour)}
+    }
+    alice := NewUserInfo("alice", "")
+    alice.Posts = []Post{post("alice", "learning #golang")}
+
+    public := NewUserInfo("public", "")
+    public.Posts = []Post{post("public", "more #golang")}
+    private := NewUserInfo("private", "")
+    private.Private = true
+    private.Posts = []Post{post("private", "secret #golang")}
+    followers := NewUserInfo("followers", "")
+    followers.Posts = []Post{post("followers", "#golang for friends")}
+    followers.Posts[0].Visibility = VisibilityFollowers
+    mentioned := NewUserInfo("mentioned", "")
+    mentioned.Posts = []Post{post("mentioned", "#golang @bob")}
+    mentioned.Posts[0].Visibility = VisibilityMentioned
+    mentioned.Posts[0].Mentions = []string{"bob"}
+    takenDown := NewUserInfo("takendown", "")
+    takenDown.Posts = []Post{post("takendown", "#golang")}
+    takenDown.Posts[0].Takedown.Active = true
+    held := NewUserInfo("held", "")
+    held.Posts = []Post{post("held", "#golang")}
+    held.Posts[0].Held = true
+    expired := NewUserInfo("expired", "")
+    expired.Posts = []Post{post("expired", "#golang")}
+    expired.Posts[0].ExpiresAt = now.Add(-time.Minute)
+
+    USERS := map[string]*UserInfo{}
+    for _, user := range []*UserInfo{alice, public, private, followers, mentioned, takenDown, held, expired} {
+        USERS[user.Username] = user
+    }
+
+    reasons := make(map[string]string)
+    for _, recommendation := range alice.Recommend(USERS, now) {
+        reasons[recommendation.User.Username] = recommendation.Reason
+    }
+    if reasons["public"] != "Also chirps about #golang" {
+        t.Errorf("public account reason = %q, want the shared hashtag", reasons["public"])
+    }
+    for _, name := range []string{"private", "followers", "mentioned", "takendown", "held", "expired"} {
+        if reason, ok := reasons[name]; ok {
+            t.Errorf("%s suggested with reason %q, its hashtag is hidden from the viewer", name, reason)
+        }
+    }
+}
@@ -0,0 +1,185 @@
+package lib
+
+import (
+    "sort"
+    "strings"
+    "time"
+)
+
+// Struct to hold a username the user renamed away from
+type PreviousName struct {
+    Name  string
+    Until time.Time  // other users cannot take the name before this time
+}
+
+// Checks if the user still reserves the name, ignoring case, after renaming away from it
+func (user *UserInfo) Reserves(name string, now time.Time) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    for _, previous := range user.PreviousNames {
+        if strings.EqualFold(previous.Name, name) && previous.Until.After(now) {
+            return true
+        }
+    }
+    return false
+}
+
+// Changes the user's username and reserves the old one until the given time
+// Taking back a reserved previous name ends its reservation, reservations that have run out are dropped
+func (user *UserInfo) Rename(name string, until time.Time, now time.Time) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    kept := []PreviousName{}
+    for _, previous := range user.PreviousNames {
+        if !strings.EqualFold(previous.Name, name) && previous.Until.After(now) {
+            kept = append(kept, previous)
+        }
+    }
+    user.PreviousNames = append(kept, PreviousName{user.Username, until})
+    user.Username = name
+}
+
+// Stands in for a purged user in the records kept after the account is gone, such as messages they sent
+const DELETED_USER = "[deleted]"
+
+/*
+    Rename references replaces every reference to the old username in the user's data with the new one:
+    follows, followers and follow requests, blocks and mutes, list members, posts and their mentions,
+    bookmarks, poll voters, notifications, reports and conversations.
+    The text of posts and messages is left as written, so an old @mention still reads the old name.
+    Returns true if anything changed and the user needs to be written.
+*/
+func (user *UserInfo) RenameReferences(old, name string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return user.replaceReferences(old, name)
+}
+
+/*
+    Remove references drops every reference to a purged user from the user's data: follows, followers and
+    follow requests, blocks and mutes, list members, mentions, bookmarks of their posts and notifications
+    from them. Reports they filed and conversations with them are kept, with DELETED_USER as the reporter,
+    member and sender, so the rest of the record still reads sensibly. Their poll votes stay in the counts
+    and are recorded under DELETED_USER, so the votes no longer belong to whoever takes the name next.
+    Returns true if anything changed and the user needs to be written.
+*/
+func (user *UserInfo) RemoveReferences(name string) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return user.replaceReferences(name, "")
+}
+
+// Replaces every reference to the old username with the new one, an empty name removes the references
+// The caller must hold the user's mutex
+func (user *UserInfo) replaceReferences(old, name string) bool {
+    changed := false
+    // replaces a name that stays in place, a removed user becomes DELETED_USER
+    replace := func(value *string) {
+        if *value == old {
+            *value = name
+            if name == "" {
+                *value = DELETED_USER
+            }
+            changed = true
+        }
+    }
+    replaceAll := func(values []string) []string {
+        kept := values[:0]
+        for _, value := range values {
+            if value == old {
+                changed = true
+                if name == "" {
+                    continue
+                }
+                value = name
+            }
+            kept = append(kept, value)
+        }
+        return kept
+    }
+    replaceKey := func(set map[string]bool) {
+        if value, ok := set[old]; ok {
+            delete(set, old)
+            if name != "" {
+                set[name] = value
+            }
+            changed = true
+        }
+    }
+
+    replaceKey(user.Following)
+    replaceKey(user.Blocked)
+    replaceKey(user.Muted)
+    user.FollowedBy = replaceAll(user.FollowedBy)
+    user.FollowRequests = replaceAll(user.FollowRequests)
+    for _, list := range user.Lists {
+        list.Members = replaceAll(list.Members)
+        sort.Strings(list.Members)
+    }
+    for i := range user.Posts {
+        replace(&user.Posts[i].Poster)
+        user.Posts[i].Mentions = replaceAll(user.Posts[i].Mentions)
+    }
+    for collection, refs := range user.Bookmarks {
+        kept := refs[:0]
+        for _, ref := range refs {
+            if ref.Poster == old {
+                changed = true
+                if name == "" {
+                    continue
+                }
+                ref.Poster = name
+            }
+            kept = append(kept, ref)
+        }
+        user.Bookmarks[collection] = kept
+    }
+    for _, voters := range user.PollVoters {
+        if voters[old] {
+            delete(voters, old)
+            if name == "" {
+                voters[DELETED_USER] = true  // the vote stays in the counts
+            } else {
+                voters[name] = true
+            }
+            changed = true
+        }
+    }
+    notifications := user.Notifications[:0]
+    for _, note := range user.Notifications {
+        if note.From == old {
+            changed = true
+            if name == "" {
+                continue
+            }
+            note.From = name
+        }
+        notifications = append(notifications, note)
+    }
+    user.Notifications = notifications
+    for i := range user.Reports {
+        replace(&user.Reports[i].Reporter)
+    }
+
+    // conversations are keyed by their members, two conversations whose members now match are merged
+    conversations := make(map[string]*Conversation)
+    for _, conv := range user.Conversations {
+        for i := range conv.Members {
+            replace(&conv.Members[i])
+        }
+        for i := range conv.Messages {
+            replace(&conv.Messages[i].Sender)
+        }
+        conv.Key = ConversationKey(conv.Members)
+        if existing, ok := conversations[conv.Key]; ok {
+            existing.Messages = append(existing.Messages, conv.Messages...)
+            sort.SliceStable(existing.Messages, func(i, j int) bool {
+                return existing.Messages[i].Stamp.Before(existing.Messages[j].Stamp)
+            })
+            continue
+        }
+        conversations[conv.Key] = conv
+    }
+    user.Conversations = conversations
+    return changed
+}
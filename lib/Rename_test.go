@@ -0,0 +1,155 @@
+package lib
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+// Builds a user whose data refers to bob everywhere a username can appear, and to carol in a few places
+func referringUser() *UserInfo {
+    start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+    user := NewUserInfo("alice", "")
+    user.Following["bob"] = true
+    user.Following["carol"] = true
+    user.Blocked["bob"] = true
+    user.Muted["bob"] = true
+    user.FollowedBy = []string{"bob", "carol"}
+    user.FollowRequests = []string{"bob"}
+    user.Lists["friends"] = &List{"friends", false, []string{"bob", "carol"}}
+    user.Posts = []Post{{Id: 1, Poster: "alice", Mentions: []string{"bob", "carol"}}}
+    user.Bookmarks[DEFAULT_COLLECTION] = []PostRef{{"bob", 1}, {"carol", 2}}
+    user.PollVoters[1] = map[string]bool{"bob": true, "carol": true}
+    user.Notifications = []Notification{{From: "bob"}, {From: "carol"}}
+    user.Reports = []Report{{Id: 1, Reporter: "bob"}}
+    user.Conversations["alice,bob"] = &Conversation{"alice,bob", []string{"alice", "bob"}, []Message{
+        {Sender: "bob", Message: "hi", Stamp: start},
+        {Sender: "alice", Message: "hello", Stamp: start.Add(2 * time.Minute)},
+    }}
+    user.Conversations["alice,dave"] = &Conversation{"alice,dave", []string{"alice", "dave"}, []Message{
+        {Sender: "dave", Message: "hey", Stamp: start.Add(time.Minute)},
+    }}
+    return user
+}
+
+func TestReplaceReferences(t *testing.T) {
+    tests := []struct {
+        name string
+        edit func(user *UserInfo) bool
+        want func(user *UserInfo)
+    }{
+        {
+            "rename",
+            func(user *UserInfo) bool { return user.RenameReferences("bob", "robert") },
+            func(user *UserInfo) {
+                user.Following = map[string]bool{"robert": true, "carol": true}
+                user.Blocked = map[string]bool{"robert": true}
+                user.Muted = map[string]bool{"robert": true}
+                user.FollowedBy = []string{"robert", "carol"}
+                user.FollowRequests = []string{"robert"}
+                user.Lists["friends"].Members = []string{"carol", "robert"}
+                user.Posts[0].Mentions = []string{"robert", "carol"}
+                user.Bookmarks[DEFAULT_COLLECTION][0].Poster = "robert"
+                user.PollVoters[1] = map[string]bool{"robert": true, "carol": true}
+                user.Notifications[0].From = "robert"
+                user.Reports[0].Reporter = "robert"
+                conv := user.Conversations["alice,bob"]
+                conv.Key, conv.Members, conv.Messages[0].Sender = "alice,robert", []string{"alice", "robert"}, "robert"
+                delete(user.Conversations, "alice,bob")
+                user.Conversations["alice,robert"] = conv
+            },
+        },
+        {
+            "remove",
+            func(user *UserInfo) bool { return user.RemoveReferences("bob") },
+            func(user *UserInfo) {
+                user.Following = map[string]bool{"carol": true}
+                user.Blocked = map[string]bool{}
+                user.Muted = map[string]bool{}
+                user.FollowedBy = []string{"carol"}
+                user.FollowRequests = []string{}
+                user.Lists["friends"].Members = []string{"carol"}
+                user.Posts[0].Mentions = []string{"carol"}
+                user.Bookmarks[DEFAULT_COLLECTION] = []PostRef{{"carol", 2}}
+                user.PollVoters[1] = map[string]bool{DELETED_USER: true, "carol": true}
+                user.Notifications = []Notification{{From: "carol"}}
+                user.Reports[0].Reporter = DELETED_USER
+                conv := user.Conversations["alice,bob"]
+                conv.Key, conv.Members, conv.Messages[0].Sender = "[deleted],alice", []string{"alice", DELETED_USER}, DELETED_USER
+                delete(user.Conversations, "alice,bob")
+                user.Conversations["[deleted],alice"] = conv
+            },
+        },
+    }
+    for _, test := range tests {
+        user, want := referringUser(), referringUser()
+        test.want(want)
+        if !test.edit(user) {
+            t.Errorf("%s: reported no change", test.name)
+        }
+        if !reflect.DeepEqual(user, want) {
+            t.Errorf("%s: got %+v, want %+v", test.name, user, want)
+        }
+        if test.edit(user) {
+            t.Errorf("%s: reported a change the second time", test.name)
+        }
+    }
+}
+
+func TestRemoveReferencesMergesConversations(t *testing.T) {
+    user := referringUser()
+    user.RemoveReferences("bob")
+    user.RemoveReferences("dave")
+    if len(user.Conversations) != 1 {
+        t.Fatalf("got %d conversations, want 1", len(user.Conversations))
+    }
+    conv := user.Conversations["[deleted],alice"]
+    if conv == nil {
+        t.Fatalf("merged conversation missing, got %v", user.Conversations)
+    }
+    var got []string
+    for _, message := range conv.Messages {
+        got = append(got, message.Sender + ": " + message.Message)
+    }
+    want := []string{"[deleted]: hi", "[deleted]: hey", "alice: hello"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("merged messages = %v, want %v", got, want)
+    }
+}
+
+func TestRenameReservations(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    day := 24 * time.Hour
+    user := NewUserInfo("alice", "")
+
+    user.Rename("alicia", now.Add(30 * day), now)
+    if user.Username != "alicia" || !user.Reserves("ALICE", now) || user.Reserves("alicia", now) {
+        t.Errorf("after renaming: username %q, previous names %+v", user.Username, user.PreviousNames)
+    }
+    if user.Reserves("alice", now.Add(30 * day)) {
+        t.Errorf("the name is still reserved when the reservation ends")
+    }
+
+    user.Rename("ally", now.Add(40 * day), now.Add(10 * day))
+    if !user.Reserves("alice", now.Add(10 * day)) || !user.Reserves("alicia", now.Add(10 * day)) {
+        t.Errorf("previous names = %+v, want alice and alicia reserved", user.PreviousNames)
+    }
+
+    user.Rename("Alice", now.Add(50 * day), now.Add(20 * day))
+    var names []string
+    for _, previous := range user.PreviousNames {
+        names = append(names, previous.Name)
+    }
+    if !reflect.DeepEqual(names, []string{"alicia", "ally"}) {
+        t.Errorf("previous names after taking alice back = %q, want [alicia ally]", names)
+    }
+
+    user.Rename("al", now.Add(70 * day), now.Add(45 * day))
+    names = nil
+    for _, previous := range user.PreviousNames {
+        names = append(names, previous.Name)
+    }
+    if !reflect.DeepEqual(names, []string{"ally", "Alice"}) {
+        t.Errorf("previous names after alicia ran out = %q, want [ally Alice]", names)
+    }
+}
@@ -24,11 +24,61 @@ type ReplicaInfo struct {
 // Values will be set in DetermineMaster
 func NewReplica() ReplicaInfo {
     gob.Register([]Post{})
+    gob.Register([]string{})
+    gob.Register([]Notification{})
+    gob.Register([]UserResult{})
+    gob.Register([]Recommendation{})
+    gob.Register(Trends{})
+    gob.Register(UserPage{})
+    gob.Register(Profile{})
+    gob.Register(Inbox{})
     gob.Register(struct{Username, Password string}{})
     gob.Register(struct{Username1, Username2 string}{})
     gob.Register(struct{Searcher, Target string}{})
-    gob.Register(struct{Username, Post string}{})
+    gob.Register(struct{Username, Post string; Lifetime time.Duration; Attachments []Attachment; Visibility int}{})
     gob.Register(struct{Id int; Serverlist []int}{})
+    gob.Register(struct{Sender string; Recipients []string; Message string}{})
+    gob.Register(struct{Username string; FollowingOnly bool}{})
+    gob.Register(struct{Username string; Id int}{})
+    gob.Register(struct{Searcher, Query string}{})
+    gob.Register(struct{Viewer, Target string; Page int}{})
+    gob.Register(struct{Username, DisplayName, Bio string}{})
+    gob.Register(struct{Username string; Private bool}{})
+    gob.Register(struct{Username, Requester string; Approve bool}{})
+    gob.Register([]ScheduledPost{})
+    gob.Register(struct{Username, Post string; PublishAt time.Time}{})
+    gob.Register(struct{Username string; Id int; Stamp time.Time}{})
+    gob.Register(struct{Username string; Retention time.Duration}{})
+    gob.Register(struct{Username string; Ids []int}{})
+    gob.Register(Attachment{})
+    gob.Register([]byte{})
+    gob.Register(struct{Viewer, Hash string}{})
+    gob.Register(struct{Username, Name string; Data []byte}{})
+    gob.Register([]List{})
+    gob.Register(ListTimeline{})
+    gob.Register(struct{Username, Name string}{})
+    gob.Register(struct{Username, Name string; Private bool}{})
+    gob.Register(struct{Username, Name, Member string}{})
+    gob.Register(struct{Viewer, Owner string}{})
+    gob.Register(struct{Viewer, Owner, Name string}{})
+    gob.Register(Bookmarks{})
+    gob.Register(struct{Username, Collection, Poster string; Id int}{})
+    gob.Register(struct{Username, Collection string}{})
+    gob.Register(struct{Username, Post string; Options []string; Duration time.Duration}{})
+    gob.Register(struct{Username, Poster string; Id, Option int}{})
+    gob.Register(struct{Username string; Id int; Counts []int}{})
+    gob.Register([]ModerationItem{})
+    gob.Register(Standing{})
+    gob.Register([]Account{})
+    gob.Register(ExportStatus{})
+    gob.Register(struct{Reporter, Target string; Id int; Reason string}{})
+    gob.Register(struct{Admin, Target string; Id, Action int; Reason string}{})
+    gob.Register(struct{Username string; Id int; Message string}{})
+    gob.Register(struct{Admin, Rules string}{})
+    gob.Register(struct{Admin, Query string}{})
+    gob.Register(struct{Admin, Target string; Role int}{})
+    gob.Register(struct{Username, NewName string}{})
+    gob.Register(Stamped{})
 
 
 	return ReplicaInfo{
@@ -88,10 +138,38 @@ func (replica *ReplicaInfo) DetermineMaster(infoChannel chan int, userChannel ch
 
 	infoChannel <- 0  // Let backend know replica info has been set
 
-	// Decode each user to copy into filesystem
+	// Decode each attachment blob until the empty blob marking the end of them
+	for {
+		var blob Blob
+		err = decoder.Decode(&blob)
+		if err != nil {
+			replica.LOG[ERROR].Println(StatusText(StatusDecodeError), err)
+			panic("Can't decode blobs for construction")
+		}
+		if blob.Hash == "" {
+			break
+		}
+		if err = WriteBlob(blob.Hash, blob.Data); err != nil {
+			replica.LOG[ERROR].Println("Unable to store blob", blob.Hash, err)
+		}
+	}
+
+	// Decode the master's filter configuration, loaded by the backend with the users
+	var config string
+	if err = decoder.Decode(&config); err != nil {
+		replica.LOG[ERROR].Println(StatusText(StatusDecodeError), err)
+		panic("Can't decode filter config for construction")
+	}
+	if err = WriteFilterConfig(config); err != nil {
+		replica.LOG[ERROR].Println("Unable to store filter config", err)
+	}
+
+	// Decode each user to copy into filesystem, a fresh UserInfo is needed for every user
+	// because gob merges decoded maps into existing ones
 	uInfo := NewUserInfo("","")
 	for decoder.Decode(uInfo) == nil {
 		userChannel <- *uInfo  // Send to server.go to call WriteUser function
+		uInfo = NewUserInfo("","")
 	}
     close(userChannel)  // Let backend know no more users to copy
     <-infoChannel       // Wait for backend to finish loading users
@@ -234,6 +312,20 @@ func (replica *ReplicaInfo) acceptNewServers(users *map[string]*UserInfo, usersL
 
         encoder := gob.NewEncoder(conn)
         encoder.Encode(request)
+        for _, hash := range ListBlobs() {
+            data, err := ReadBlob(hash)
+            if err != nil {
+                replica.LOG[ERROR].Println("Unable to read blob", hash, err)
+                continue
+            }
+            encoder.Encode(Blob{hash, data})
+        }
+        encoder.Encode(Blob{})  // an empty blob ends the list of blobs
+        config, err := ReadFilterConfig()
+        if err != nil {
+            replica.LOG[ERROR].Println("Unable to read filter config", err)
+        }
+        encoder.Encode(config)
         usersLock.RLock()
         for _, user := range *users {
             err = encoder.Encode(*user)
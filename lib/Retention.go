@@ -0,0 +1,67 @@
+package lib
+
+import "time"
+
+// Checks if the post has an expiry time that has passed
+func (post Post) Expired(now time.Time) bool {
+    return !post.ExpiresAt.IsZero() && !post.ExpiresAt.After(now)
+}
+
+// Sets how long the user's posts are kept, zero keeps them forever
+func (user *UserInfo) SetRetention(retention time.Duration) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    user.Retention = retention
+}
+
+// Returns how long the user's posts are kept, zero means forever
+func (user *UserInfo) GetRetention() time.Duration {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return user.Retention
+}
+
+// Returns the ids of the user's posts that have expired or are older than the retention period
+// The shorter of the user's retention and the cluster retention applies, zero means no limit
+func (user *UserInfo) ExpiredPosts(now time.Time, clusterRetention time.Duration) []int {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    retention := user.Retention
+    if retention == 0 || (clusterRetention > 0 && clusterRetention < retention) {
+        retention = clusterRetention
+    }
+    var ids []int
+    for _, post := range user.Posts {
+        if post.Expired(now) || (retention > 0 && post.Stamp.Before(now.Add(-retention))) {
+            ids = append(ids, post.Id)
+        }
+    }
+    return ids
+}
+
+// Removes the posts with the given ids and returns the ids that were found and removed
+// A removed post that was pinned is unpinned and open reports against removed posts are closed
+func (user *UserInfo) DeletePosts(ids []int) []int {
+    remove := make(map[int]bool)
+    for _, id := range ids {
+        remove[id] = true
+    }
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    var removed []int
+    kept := user.Posts[:0]
+    for _, post := range user.Posts {
+        if remove[post.Id] {
+            removed = append(removed, post.Id)
+            if user.Pinned == post.Id {
+                user.Pinned = 0
+            }
+            delete(user.PollVoters, post.Id)
+            user.resolveReports(post.Id)
+        } else {
+            kept = append(kept, post)
+        }
+    }
+    user.Posts = kept
+    return removed
+}
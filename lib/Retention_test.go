@@ -0,0 +1,72 @@
+package lib
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestExpired(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    tests := []struct {
+        expiresAt time.Time
+        want      bool
+    }{
+        {time.Time{}, false},
+        {now.Add(time.Second), false},
+        {now, true},
+        {now.Add(-time.Hour), true},
+    }
+    for _, test := range tests {
+        if got := (Post{ExpiresAt: test.expiresAt}).Expired(now); got != test.want {
+            t.Errorf("Expired with ExpiresAt %v = %v, want %v", test.expiresAt, got, test.want)
+        }
+    }
+}
+
+func TestExpiredPosts(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    day := 24 * time.Hour
+    user := NewUserInfo("alice", "")
+    user.Posts = []Post{
+        {Id: 1, Stamp: now.Add(-10 * day)},
+        {Id: 2, Stamp: now.Add(-3 * day)},
+        {Id: 3, Stamp: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)},
+        {Id: 4, Stamp: now.Add(-time.Hour), ExpiresAt: now.Add(time.Minute)},
+    }
+    tests := []struct {
+        name      string
+        retention time.Duration
+        cluster   time.Duration
+        want      []int
+    }{
+        {"no limits", 0, 0, []int{3}},
+        {"user retention", 7 * day, 0, []int{1, 3}},
+        {"cluster retention", 0, 2 * day, []int{1, 2, 3}},
+        {"shorter user retention", 2 * day, 7 * day, []int{1, 2, 3}},
+        {"shorter cluster retention", 30 * day, 7 * day, []int{1, 3}},
+    }
+    for _, test := range tests {
+        user.SetRetention(test.retention)
+        if got := user.ExpiredPosts(now, test.cluster); !reflect.DeepEqual(got, test.want) {
+            t.Errorf("%s: ExpiredPosts = %v, want %v", test.name, got, test.want)
+        }
+    }
+}
+
+func TestDeletePosts(t *testing.T) {
+    user := NewUserInfo("alice", "")
+    user.Posts = []Post{{Id: 1}, {Id: 2}, {Id: 3}}
+    user.Pinned = 2
+    user.PollVoters[2] = map[string]bool{"bob": true}
+
+    if got := user.DeletePosts([]int{2, 3, 7}); !reflect.DeepEqual(got, []int{2, 3}) {
+        t.Errorf("DeletePosts removed %v, want [2 3]", got)
+    }
+    if len(user.Posts) != 1 || user.Posts[0].Id != 1 {
+        t.Errorf("posts left = %+v, want only post 1", user.Posts)
+    }
+    if user.Pinned != 0 || user.PollVoters[2] != nil {
+        t.Errorf("removed post is still pinned or keeps its voters")
+    }
+}
@@ -0,0 +1,93 @@
+package lib
+
+import (
+    "sort"
+    "strings"
+)
+
+// Roles of an account, each role can do everything the roles before it can
+const (
+    RoleUser = iota   // an ordinary account
+    RoleModerator     // reviews reports, takes down posts and suspends accounts
+    RoleAdmin         // also manages accounts, roles and the content filters
+)
+
+var ROLE_NAMES = []string{"User", "Moderator", "Admin"}  // names of the roles indexed by role
+
+// Struct to hold an account as listed in the admin area
+type Account struct {
+    Username  string
+    Role      int
+    Suspended bool
+    Deleted   bool  // waiting to be purged after the grace period
+    Joined    string
+    Posts     int
+    Followers int
+}
+
+// Checks if a role value is one of the known roles
+func ValidRole(role int) bool {
+    return role >= RoleUser && role <= RoleAdmin
+}
+
+// Returns the name of the role
+func RoleName(role int) string {
+    if !ValidRole(role) {
+        return ""
+    }
+    return ROLE_NAMES[role]
+}
+
+// Returns the user's role
+func (user *UserInfo) GetRole() int {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return user.Role
+}
+
+// Checks if the user has the given role or a higher one
+func (user *UserInfo) HasRole(role int) bool {
+    return user.GetRole() >= role
+}
+
+// Sets the user's role
+func (user *UserInfo) SetRole(role int) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    user.Role = role
+}
+
+// Returns the name of the account's role
+func (account Account) RoleName() string {
+    return RoleName(account.Role)
+}
+
+// Lists every account whose username contains the query, ignoring case, sorted by username
+func Accounts(USERS map[string]*UserInfo, query string) []Account {
+    query = strings.ToLower(strings.TrimSpace(query))
+    accounts := []Account{}
+    for name, user := range USERS {
+        if !strings.Contains(strings.ToLower(name), query) {
+            continue
+        }
+        followers := user.FollowerCount()
+        user.mut.Lock()
+        account := Account{
+            Username:  name,
+            Role:      user.Role,
+            Suspended: user.Suspension.Active,
+            Deleted:   !user.DeletedAt.IsZero(),
+            Posts:     len(user.Posts),
+            Followers: followers,
+        }
+        if !user.Joined.IsZero() {
+            account.Joined = user.Joined.Format("2 January 2006")
+        }
+        user.mut.Unlock()
+        accounts = append(accounts, account)
+    }
+    sort.Slice(accounts, func(i, j int) bool {
+        return accounts[i].Username < accounts[j].Username
+    })
+    return accounts
+}
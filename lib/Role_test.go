@@ -0,0 +1,70 @@
+package lib
+
+import (
+    "testing"
+    "time"
+)
+
+func TestRoleChecks(t *testing.T) {
+    tests := []struct {
+        role             int
+        name             string
+        moderator, admin bool
+    }{
+        {RoleUser, "User", false, false},
+        {RoleModerator, "Moderator", true, false},
+        {RoleAdmin, "Admin", true, true},
+    }
+    for _, test := range tests {
+        user := NewUserInfo("alice", "")
+        user.SetRole(test.role)
+        if user.GetRole() != test.role || !user.HasRole(RoleUser) {
+            t.Errorf("%s: role = %d", test.name, user.GetRole())
+        }
+        if user.HasRole(RoleModerator) != test.moderator || user.HasRole(RoleAdmin) != test.admin {
+            t.Errorf("%s: HasRole(moderator) = %v, HasRole(admin) = %v", test.name, user.HasRole(RoleModerator), user.HasRole(RoleAdmin))
+        }
+        standing := user.GetStanding()
+        if standing.CanModerate() != test.moderator || standing.CanAdminister() != test.admin {
+            t.Errorf("%s: standing can moderate %v, administer %v", test.name, standing.CanModerate(), standing.CanAdminister())
+        }
+        if RoleName(test.role) != test.name || !ValidRole(test.role) {
+            t.Errorf("RoleName(%d) = %q, want %q", test.role, RoleName(test.role), test.name)
+        }
+    }
+    for _, role := range []int{-1, RoleAdmin + 1} {
+        if ValidRole(role) || RoleName(role) != "" {
+            t.Errorf("role %d is valid or named %q", role, RoleName(role))
+        }
+    }
+}
+
+func TestAccounts(t *testing.T) {
+    now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+    USERS := map[string]*UserInfo{}
+    for _, name := range []string{"carol", "Alice", "bob", "malice"} {
+        USERS[name] = NewUserInfo(name, "")
+    }
+    USERS["Alice"].SetRole(RoleAdmin)
+    USERS["Alice"].Joined = now
+    USERS["Alice"].Posts = []Post{{Id: 1}, {Id: 2}}
+    USERS["bob"].Follow(USERS["Alice"])
+    USERS["malice"].Suspend("spam")
+    USERS["carol"].MarkDeleted(now)
+
+    accounts := Accounts(USERS, " ALI ")
+    if len(accounts) != 2 || accounts[0].Username != "Alice" || accounts[1].Username != "malice" {
+        t.Fatalf("Accounts(ALI) = %+v, want Alice and malice", accounts)
+    }
+    alice := accounts[0]
+    if alice.RoleName() != "Admin" || alice.Joined != "14 March 2026" || alice.Posts != 2 || alice.Followers != 1 {
+        t.Errorf("Alice = %+v", alice)
+    }
+    if !accounts[1].Suspended {
+        t.Errorf("malice is not listed as suspended")
+    }
+    all := Accounts(USERS, "")
+    if len(all) != 4 || all[0].Username != "Alice" || !all[2].Deleted {
+        t.Errorf("Accounts() = %+v, want every account by username with carol deleted", all)
+    }
+}
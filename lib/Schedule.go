@@ -0,0 +1,61 @@
+package lib
+
+import (
+    "sort"
+    "time"
+)
+
+// Struct to hold a post waiting to be published at a later time
+type ScheduledPost struct {
+    Id        int  // unique among the user's scheduled posts, ids start at 1
+    Message   string
+    PublishAt time.Time
+}
+
+// Stores a post to be published at the given time and returns a copy of it
+func (user *UserInfo) SchedulePost(msg string, publishAt time.Time) ScheduledPost {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    user.LastScheduleId++
+    scheduled := ScheduledPost{user.LastScheduleId, msg, publishAt}
+    user.Scheduled = append(user.Scheduled, scheduled)
+    return scheduled
+}
+
+// Returns a copy of the user's scheduled posts, soonest first
+func (user *UserInfo) GetScheduled() []ScheduledPost {
+    user.mut.Lock()
+    scheduled := make([]ScheduledPost, len(user.Scheduled))
+    copy(scheduled, user.Scheduled)
+    user.mut.Unlock()
+    sort.Slice(scheduled, func(i, j int) bool {
+        return scheduled[i].PublishAt.Before(scheduled[j].PublishAt)
+    })
+    return scheduled
+}
+
+// Returns the scheduled posts whose publish time is not after now
+func (user *UserInfo) DueScheduled(now time.Time) []ScheduledPost {
+    var due []ScheduledPost
+    for _, scheduled := range user.GetScheduled() {
+        if scheduled.PublishAt.After(now) {
+            break
+        }
+        due = append(due, scheduled)
+    }
+    return due
+}
+
+// Removes the scheduled post with the given id and returns it, returns false if there is none
+// Publishing and cancelling both take the post out first so it can never be published twice
+func (user *UserInfo) TakeScheduled(id int) (ScheduledPost, bool) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    for i, scheduled := range user.Scheduled {
+        if scheduled.Id == id {
+            user.Scheduled = append(user.Scheduled[:i], user.Scheduled[i+1:]...)
+            return scheduled, true
+        }
+    }
+    return ScheduledPost{}, false
+}
@@ -0,0 +1,53 @@
+package lib
+
+import (
+    "testing"
+    "time"
+)
+
+func TestScheduledPosts(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    user := NewUserInfo("alice", "")
+    later := user.SchedulePost("later", now.Add(time.Hour))
+    soon := user.SchedulePost("soon", now.Add(time.Minute))
+    user.SchedulePost("exactly now", now.Add(30 * time.Second))
+    if later.Id != 1 || soon.Id != 2 {
+        t.Errorf("ids = %d, %d, want 1 and 2", later.Id, soon.Id)
+    }
+
+    var got []string
+    for _, scheduled := range user.GetScheduled() {
+        got = append(got, scheduled.Message)
+    }
+    if len(got) != 3 || got[0] != "exactly now" || got[1] != "soon" || got[2] != "later" {
+        t.Errorf("scheduled = %q, want soonest first", got)
+    }
+
+    tests := []struct {
+        at   time.Time
+        want int
+    }{
+        {now, 0},
+        {now.Add(30 * time.Second), 1},
+        {now.Add(time.Minute), 2},
+        {now.Add(2 * time.Hour), 3},
+    }
+    for _, test := range tests {
+        if due := user.DueScheduled(test.at); len(due) != test.want {
+            t.Errorf("DueScheduled(%v) = %+v, want %d posts", test.at, due, test.want)
+        }
+    }
+
+    if taken, ok := user.TakeScheduled(soon.Id); !ok || taken.Message != "soon" {
+        t.Errorf("TakeScheduled(%d) = %+v, %v", soon.Id, taken, ok)
+    }
+    if _, ok := user.TakeScheduled(soon.Id); ok {
+        t.Errorf("a scheduled post was taken twice")
+    }
+    if due := user.DueScheduled(now.Add(2 * time.Hour)); len(due) != 2 {
+        t.Errorf("taken post is still due: %+v", due)
+    }
+    if next := user.SchedulePost("next", now); next.Id != 4 {
+        t.Errorf("new id = %d after taking a post, want 4", next.Id)
+    }
+}
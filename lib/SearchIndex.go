@@ -0,0 +1,197 @@
+package lib
+
+import (
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "unicode"
+)
+
+const MAX_SEARCH_RESULTS = 50  // most chirps returned by a single search
+
+var hashtagPattern = regexp.MustCompile(`#(\w+)`)  // matches #hashtag inside a chirp
+
+// Struct to identify a single post across all users
+type PostRef struct {
+    Poster string
+    Id     int
+}
+
+// Struct to hold a parsed chirp search, "quoted phrases" must appear in order and from:username limits the author
+type ChirpQuery struct {
+    Terms   []string
+    Phrases [][]string
+    Author  string
+}
+
+// Struct to hold an inverted index from each term to the posts containing it
+type ChirpIndex struct {
+    terms  map[string]map[PostRef]bool
+    tokens map[PostRef][]string
+    stamps map[PostRef]time.Time
+    mut    *sync.RWMutex
+}
+
+// Creates an empty chirp index
+func NewChirpIndex() *ChirpIndex {
+    return &ChirpIndex{
+        terms:  make(map[string]map[PostRef]bool),
+        tokens: make(map[PostRef][]string),
+        stamps: make(map[PostRef]time.Time),
+        mut:    &sync.RWMutex{},
+    }
+}
+
+// Splits text into lower case terms made of letters, digits and underscores
+func Tokenize(text string) []string {
+    return strings.FieldsFunc(strings.ToLower(text), func(c rune) bool {
+        return !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_'
+    })
+}
+
+// Finds every #hashtag in the text, lower cased and listed once each
+func Hashtags(text string) []string {
+    var tags []string
+    seen := make(map[string]bool)
+    for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+        tag := strings.ToLower(match[1])
+        if !seen[tag] {
+            seen[tag] = true
+            tags = append(tags, tag)
+        }
+    }
+    return tags
+}
+
+// Parses the raw search text into terms, quoted phrases and an optional from:username author filter
+func ParseChirpQuery(text string) ChirpQuery {
+    var query ChirpQuery
+    for i, part := range strings.Split(text, "\"") {
+        if i % 2 == 1 {  // odd parts were inside quotes
+            if phrase := Tokenize(part); len(phrase) > 0 {
+                query.Phrases = append(query.Phrases, phrase)
+            }
+            continue
+        }
+        for _, word := range strings.Fields(part) {
+            if strings.HasPrefix(word, "from:") {
+                query.Author = strings.TrimPrefix(strings.TrimPrefix(word, "from:"), "@")
+                continue
+            }
+            query.Terms = append(query.Terms, Tokenize(word)...)
+        }
+    }
+    return query
+}
+
+// Adds a post to the index
+func (index *ChirpIndex) Add(post Post) {
+    index.mut.Lock()
+    defer index.mut.Unlock()
+    ref := PostRef{post.Poster, post.Id}
+    tokens := Tokenize(post.Message)
+    index.tokens[ref] = tokens
+    index.stamps[ref] = post.Stamp
+    for _, term := range tokens {
+        if index.terms[term] == nil {
+            index.terms[term] = make(map[PostRef]bool)
+        }
+        index.terms[term][ref] = true
+    }
+}
+
+// Removes a post from the index
+func (index *ChirpIndex) Remove(ref PostRef) {
+    index.mut.Lock()
+    defer index.mut.Unlock()
+    for _, term := range index.tokens[ref] {
+        delete(index.terms[term], ref)
+        if len(index.terms[term]) == 0 {
+            delete(index.terms, term)
+        }
+    }
+    delete(index.tokens, ref)
+    delete(index.stamps, ref)
+}
+
+// Removes every post from the index
+func (index *ChirpIndex) Clear() {
+    index.mut.Lock()
+    defer index.mut.Unlock()
+    index.terms = make(map[string]map[PostRef]bool)
+    index.tokens = make(map[PostRef][]string)
+    index.stamps = make(map[PostRef]time.Time)
+}
+
+// Finds every post matching all terms, phrases and the author of the query, the newest posts first
+// Every match is returned, callers leave out the posts the searcher cannot see before cutting to MAX_SEARCH_RESULTS
+func (index *ChirpIndex) Search(query ChirpQuery) []PostRef {
+    index.mut.RLock()
+    defer index.mut.RUnlock()
+    required := append([]string{}, query.Terms...)
+    for _, phrase := range query.Phrases {
+        required = append(required, phrase...)
+    }
+
+    var candidates map[PostRef]bool
+    if len(required) == 0 {
+        if query.Author == "" {
+            return []PostRef{}
+        }
+        candidates = make(map[PostRef]bool)
+        for ref := range index.tokens {
+            candidates[ref] = true
+        }
+    } else {
+        // start from the rarest term so the intersection stays small
+        sort.Slice(required, func(i, j int) bool {
+            return len(index.terms[required[i]]) < len(index.terms[required[j]])
+        })
+        candidates = index.terms[required[0]]
+    }
+
+    result := []PostRef{}
+    for ref := range candidates {
+        if query.Author != "" && ref.Poster != query.Author {
+            continue
+        }
+        matches := true
+        for _, term := range required {
+            if !index.terms[term][ref] {
+                matches = false
+                break
+            }
+        }
+        for _, phrase := range query.Phrases {
+            if matches && !containsPhrase(index.tokens[ref], phrase) {
+                matches = false
+            }
+        }
+        if matches {
+            result = append(result, ref)
+        }
+    }
+    sort.Slice(result, func(i, j int) bool {
+        return index.stamps[result[j]].Before(index.stamps[result[i]])
+    })
+    return result
+}
+
+// Checks if the phrase appears as consecutive tokens
+func containsPhrase(tokens, phrase []string) bool {
+    for i := 0; i + len(phrase) <= len(tokens); i++ {
+        found := true
+        for j := range phrase {
+            if tokens[i+j] != phrase[j] {
+                found = false
+                break
+            }
+        }
+        if found {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,117 @@
+package lib
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestTokenize(t *testing.T) {
+    tests := []struct {
+        text string
+        want []string
+    }{
+        {"", []string{}},
+        {"Hello, World!", []string{"hello", "world"}},
+        {"snake_case and #tags @names", []string{"snake_case", "and", "tags", "names"}},
+        {"caf\u00e9 2026", []string{"caf\u00e9", "2026"}},
+    }
+    for _, test := range tests {
+        if got := Tokenize(test.text); !reflect.DeepEqual(got, test.want) {
+            t.Errorf("Tokenize(%q) = %q, want %q", test.text, got, test.want)
+        }
+    }
+}
+
+func TestHashtags(t *testing.T) {
+    tests := []struct {
+        text string
+        want []string
+    }{
+        {"no tags", nil},
+        {"#Go and #golang", []string{"go", "golang"}},
+        {"#go #GO #Go", []string{"go"}},
+    }
+    for _, test := range tests {
+        if got := Hashtags(test.text); !reflect.DeepEqual(got, test.want) {
+            t.Errorf("Hashtags(%q) = %q, want %q", test.text, got, test.want)
+        }
+    }
+}
+
+func TestParseChirpQuery(t *testing.T) {
+    tests := []struct {
+        text string
+        want ChirpQuery
+    }{
+        {"go tips", ChirpQuery{Terms: []string{"go", "tips"}}},
+        {"\"Go Tips\" today", ChirpQuery{Terms: []string{"today"}, Phrases: [][]string{{"go", "tips"}}}},
+        {"from:@alice tea", ChirpQuery{Terms: []string{"tea"}, Author: "alice"}},
+        {"from:bob", ChirpQuery{Author: "bob"}},
+        {"\"\" \"!!\"", ChirpQuery{}},
+    }
+    for _, test := range tests {
+        if got := ParseChirpQuery(test.text); !reflect.DeepEqual(got, test.want) {
+            t.Errorf("ParseChirpQuery(%q) = %+v, want %+v", test.text, got, test.want)
+        }
+    }
+}
+
+func TestChirpIndexSearch(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    index := NewChirpIndex()
+    posts := []Post{
+        {Id: 1, Poster: "alice", Message: "Tips for Go programmers", Stamp: now},
+        {Id: 2, Poster: "bob", Message: "go tips: use gofmt", Stamp: now.Add(time.Minute)},
+        {Id: 3, Poster: "alice", Message: "tips go here", Stamp: now.Add(2 * time.Minute)},
+        {Id: 4, Poster: "carol", Message: "tea time", Stamp: now.Add(3 * time.Minute)},
+    }
+    for _, post := range posts {
+        index.Add(post)
+    }
+    tests := []struct {
+        query string
+        want  []PostRef
+    }{
+        {"tips go", []PostRef{{"alice", 3}, {"bob", 2}, {"alice", 1}}},
+        {"\"go tips\"", []PostRef{{"bob", 2}}},
+        {"\"tips go\"", []PostRef{{"alice", 3}}},
+        {"from:alice tips", []PostRef{{"alice", 3}, {"alice", 1}}},
+        {"from:carol", []PostRef{{"carol", 4}}},
+        {"TEA", []PostRef{{"carol", 4}}},
+        {"coffee", []PostRef{}},
+        {"", []PostRef{}},
+    }
+    for _, test := range tests {
+        if got := index.Search(ParseChirpQuery(test.query)); !reflect.DeepEqual(got, test.want) {
+            t.Errorf("Search(%q) = %v, want %v", test.query, got, test.want)
+        }
+    }
+
+    index.Remove(PostRef{"bob", 2})
+    if got := index.Search(ParseChirpQuery("gofmt")); len(got) != 0 {
+        t.Errorf("removed post still found: %v", got)
+    }
+    if got := index.Search(ParseChirpQuery("tips")); len(got) != 2 {
+        t.Errorf("Search(tips) after remove = %v, want 2 posts", got)
+    }
+    index.Clear()
+    if got := index.Search(ParseChirpQuery("from:alice")); len(got) != 0 {
+        t.Errorf("cleared index still finds %v", got)
+    }
+}
+
+func TestChirpIndexSearchReturnsEveryMatch(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    index := NewChirpIndex()
+    for i := 1; i <= MAX_SEARCH_RESULTS + 10; i++ {
+        index.Add(Post{Id: i, Poster: "alice", Message: "daily tea", Stamp: now.Add(time.Duration(i) * time.Minute)})
+    }
+    got := index.Search(ParseChirpQuery("tea"))
+    if len(got) != MAX_SEARCH_RESULTS + 10 {
+        t.Fatalf("got %d results, want all %d matches", len(got), MAX_SEARCH_RESULTS + 10)
+    }
+    if got[0].Id != MAX_SEARCH_RESULTS + 10 || got[len(got)-1].Id != 1 {
+        t.Errorf("results run from %d to %d, want newest first", got[0].Id, got[len(got)-1].Id)
+    }
+}
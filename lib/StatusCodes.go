@@ -1,5 +1,7 @@
 package lib
 
+import "time"
+
 // COMMANDS (frontend to backend server commands)
 // With replication, the master will also send these commands to replicas
 const (
@@ -15,6 +17,63 @@ const (
 	CommandConstructFilesystem
     CommandNewServer
     CommandDeadServer
+    CommandGetNotifications
+    CommandReadNotifications
+    CommandSendMessage
+    CommandGetConversations
+    CommandSetMessagePrivacy
+    CommandDeleteChirp
+    CommandSearchChirps
+    CommandSearchUsers
+    CommandGetFollowers
+    CommandGetFollowing
+    CommandGetProfile
+    CommandEditProfile
+    CommandSetPrivacy
+    CommandGetFollowRequests
+    CommandAnswerFollowRequest
+    CommandBlock
+    CommandUnblock
+    CommandMute
+    CommandUnmute
+    CommandRecommend
+    CommandGetTrending
+    CommandScheduleChirp
+    CommandGetScheduled
+    CommandPublishScheduled
+    CommandCancelScheduled
+    CommandSetRetention
+    CommandExpireChirps
+    CommandUploadBlob
+    CommandGetBlob
+    CommandCreateList
+    CommandDeleteList
+    CommandAddToList
+    CommandRemoveFromList
+    CommandGetLists
+    CommandGetListTimeline
+    CommandBookmark
+    CommandRemoveBookmark
+    CommandGetBookmarks
+    CommandPin
+    CommandPollChirp
+    CommandVote
+    CommandClosePoll
+    CommandReport
+    CommandGetModerationQueue
+    CommandModerate
+    CommandAppeal
+    CommandGetStanding
+    CommandGetFilters
+    CommandSetFilters
+    CommandGetAccounts
+    CommandSetRole
+    CommandExport
+    CommandGetExport
+    CommandDownloadExport
+    CommandPurgeAccount
+    CommandRestoreAccount
+    CommandRename
 )
 
 // STATUS CODES (Status Codes for frontend/backend communication)
@@ -30,6 +89,37 @@ const (
 	StatusInternalError
 	StatusEncodeError
 	StatusDecodeError
+	StatusMessageRejected
+	StatusTooManyRecipients
+	StatusPostNotFound
+	StatusInvalidProfile
+	StatusFollowRequested
+	StatusBlocked
+	StatusInvalidSchedule
+	StatusInvalidRetention
+	StatusInvalidAttachment
+	StatusBlobNotFound
+	StatusListNotFound
+	StatusInvalidList
+	StatusInvalidBookmark
+	StatusInvalidPoll
+	StatusPollClosed
+	StatusAlreadyVoted
+	StatusInvalidVisibility
+	StatusEmptyPost
+	StatusPostTooLong
+	StatusForbidden
+	StatusSuspended
+	StatusInvalidReport
+	StatusInvalidModeration
+	StatusFiltered
+	StatusInvalidFilters
+	StatusInvalidRole
+	StatusExportNotReady
+	StatusAccountDeleted
+	StatusNameReserved
+	StatusInvalidUsername
+	StatusMessageTooLong
 )
 
 // Message associated with each status
@@ -45,6 +135,37 @@ var statusText = map[int]string {
 	StatusInternalError:     "I'm sorry dave, I'm afriad I can't do that",
 	StatusEncodeError:       "Gob Encode Error",
 	StatusDecodeError:       "Gob Decode Error",
+	StatusMessageRejected:   "Recipient Only Accepts Messages From Users They Follow",
+	StatusTooManyRecipients: "Too Many Recipients For A Conversation",
+	StatusPostNotFound:      "Post Does Not Exist",
+	StatusInvalidProfile:    "Display Name Or Bio Is Too Long",
+	StatusFollowRequested:   "Follow Request Sent",
+	StatusBlocked:           "User Is Blocked",
+	StatusInvalidSchedule:   "Scheduled Time Must Be In The Future",
+	StatusInvalidRetention:  "Retention Period Cannot Be Negative",
+	StatusInvalidAttachment: "Attachment Is Too Large Or Not A Supported Type",
+	StatusBlobNotFound:      "Attachment Does Not Exist",
+	StatusListNotFound:      "List Does Not Exist",
+	StatusInvalidList:       "List Name Is Invalid Or Taken, Or The Limit Is Reached",
+	StatusInvalidBookmark:   "Collection Name Is Invalid Or The Limit Is Reached",
+	StatusInvalidPoll:       "Polls Need 2 To 4 Short Options And Must Run Between 5 Minutes And 7 Days",
+	StatusPollClosed:        "Poll Is Closed",
+	StatusAlreadyVoted:      "Already Voted In This Poll",
+	StatusInvalidVisibility: "Unknown Post Visibility",
+	StatusEmptyPost:         "Post Cannot Be Empty",
+	StatusPostTooLong:       "Post Is Longer Than 100 Characters",
+	StatusForbidden:         "Your Role Does Not Allow That",
+	StatusSuspended:         "Account Is Suspended",
+	StatusInvalidReport:     "A Reason Of 1 To 200 Characters Is Required",
+	StatusInvalidModeration: "That Action Does Not Apply Here",
+	StatusFiltered:          "Rejected By The Content Filters",
+	StatusInvalidFilters:    "Filter Rules Are Not Valid",
+	StatusInvalidRole:       "Unknown Role Or Admins Cannot Change Their Own Role",
+	StatusExportNotReady:    "The Archive Is Not Ready, Request An Export First",
+	StatusAccountDeleted:    "Account Is Deleted",
+	StatusNameReserved:      "Username Was Recently Used By Another Account",
+	StatusInvalidUsername:   "Usernames Need 3 To 20 Letters, Digits Or Underscores And Cannot Be Reserved",
+	StatusMessageTooLong:    "Message Is Longer Than 500 Characters",
 }
 
 // Function to convert a status code to the associated message
@@ -58,6 +179,27 @@ type CommandRequest struct {
 	Data        interface{}
 }
 
+// Struct wrapping a request's data with the time the master accepted the request
+// The master stamps every request before propagating it, so every server applies it with the same clock
+type Stamped struct {
+	Stamp time.Time
+	Data  interface{}
+}
+
+// Returns the request with its data stamped with the given time
+func (request CommandRequest) Stamp(now time.Time) CommandRequest {
+	return CommandRequest{request.CommandCode, Stamped{now, request.Data}}
+}
+
+// Returns the request with its stamp removed and the time it was stamped with
+// Requests that were never stamped are returned as they are with the current time
+func (request CommandRequest) Unstamp() (CommandRequest, time.Time) {
+	if stamped, ok := request.Data.(Stamped); ok {
+		return CommandRequest{request.CommandCode, stamped.Data}, stamped.Stamp
+	}
+	return request, time.Now()
+}
+
 // Struct for uniform communication from backend to frontend
 type CommandResponse struct {
 	Success bool
@@ -0,0 +1,95 @@
+package lib
+
+import (
+    "io/ioutil"
+    "os"
+    "strings"
+)
+
+const DATA_DIR = "../../data"                 // directory holding the users, blobs and exports directories
+const USER_DIR_NAME = "users"                 // name of the directory holding one file per user
+const USER_DIR = DATA_DIR + "/" + USER_DIR_NAME
+const USER_FILE_EXT = ".gob"
+const LEGACY_DIR_PREFIX = ".legacy"           // prefix of directories holding old user files moved out of the way
+
+/*
+    Storage key maps a username to a name that is safe to use as a file name on any filesystem.
+    Lowercase ASCII letters, digits and underscores are kept, every other byte, uppercase letters included,
+    becomes "=" and two lowercase hex digits. Keys never contain "/", "." or uppercase letters, so they
+    cannot leave the data directory, cannot be hidden files and two usernames differing only in case
+    get different files even where the filesystem ignores case.
+*/
+func StorageKey(username string) string {
+    const hex = "0123456789abcdef"
+    var key strings.Builder
+    for i := 0; i < len(username); i++ {
+        c := username[i]
+        if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' {
+            key.WriteByte(c)
+            continue
+        }
+        key.WriteByte('=')
+        key.WriteByte(hex[c >> 4])
+        key.WriteByte(hex[c & 0xf])
+    }
+    return key.String()
+}
+
+// Returns the path of the file holding the user's data
+func UserFile(username string) string {
+    return USER_DIR + "/" + StorageKey(username) + USER_FILE_EXT
+}
+
+/*
+    Make user dir creates the directory for user files in the given data directory. Older versions stored
+    each user directly in the data directory in a file named by the raw username, so a user may be named
+    like the directory. That user's file is first moved into a new hidden directory, where LegacyUserFiles
+    still finds it.
+*/
+func MakeUserDir(dataDir string) error {
+    dir := dataDir + "/" + USER_DIR_NAME
+    if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+        temp, err := ioutil.TempDir(dataDir, LEGACY_DIR_PREFIX)
+        if err != nil {
+            return err
+        }
+        if err = os.Rename(dir, temp + "/" + USER_DIR_NAME); err != nil {
+            return err
+        }
+    }
+    return os.MkdirAll(dir, os.ModePerm)
+}
+
+/*
+    Legacy user files returns the paths of the user files older versions wrote directly in the given data
+    directory, along with any MakeUserDir moved aside. Every regular file there is one whatever its name,
+    since user files are now only written to the user directory, so a raw username ending in ".gob" or
+    spelled like a storage key cannot be mistaken for a current file.
+*/
+func LegacyUserFiles(dataDir string) ([]string, error) {
+    files, err := ioutil.ReadDir(dataDir)
+    if err != nil {
+        return nil, err
+    }
+    var paths []string
+    for _, file := range files {
+        path := dataDir + "/" + file.Name()
+        if file.Mode().IsRegular() {
+            paths = append(paths, path)
+            continue
+        }
+        if !file.IsDir() || !strings.HasPrefix(file.Name(), LEGACY_DIR_PREFIX) {
+            continue
+        }
+        moved, err := ioutil.ReadDir(path)
+        if err != nil {
+            return nil, err
+        }
+        for _, file := range moved {
+            if file.Mode().IsRegular() {
+                paths = append(paths, path + "/" + file.Name())
+            }
+        }
+    }
+    return paths, nil
+}
@@ -0,0 +1,120 @@
+package lib
+
+import (
+    "io/ioutil"
+    "os"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+// Reverses StorageKey, returns false if the key could not have come from it
+func keyUsername(key string) (string, bool) {
+    var name []byte
+    for i := 0; i < len(key); i++ {
+        if key[i] != '=' {
+            name = append(name, key[i])
+            continue
+        }
+        if i + 3 > len(key) {
+            return "", false
+        }
+        c, err := strconv.ParseUint(key[i+1:i+3], 16, 8)
+        if err != nil {
+            return "", false
+        }
+        name = append(name, byte(c))
+        i += 2
+    }
+    return string(name), true
+}
+
+func TestStorageKey(t *testing.T) {
+    tests := []struct {
+        username, key string
+    }{
+        {"alice", "alice"},
+        {"bob_42", "bob_42"},
+        {"Bob", "=42ob"},
+        {"x.gob", "x=2egob"},
+        {"../etc", "=2e=2e=2fetc"},
+        {"a=b", "a=3db"},
+        {"a b", "a=20b"},
+        {"é", "=c3=a9"},
+        {"", ""},
+    }
+    for _, test := range tests {
+        key := StorageKey(test.username)
+        if key != test.key {
+            t.Errorf("StorageKey(%q) = %q, want %q", test.username, key, test.key)
+        }
+        if name, ok := keyUsername(key); !ok || name != test.username {
+            t.Errorf("StorageKey(%q) = %q reads back as %q", test.username, key, name)
+        }
+        if strings.ContainsAny(key, "/.ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+            t.Errorf("StorageKey(%q) = %q is not safe as a file name", test.username, key)
+        }
+    }
+}
+
+func TestStorageKeyCollisions(t *testing.T) {
+    names := []string{"bob", "Bob", "BOB", "bOb", "bob=42", "=62ob", "x.gob", "x=2egob", "x_gob", "xgob", "a b", "a=20b"}
+    seen := make(map[string]string)
+    for _, name := range names {
+        key := StorageKey(name)
+        if other, ok := seen[key]; ok {
+            t.Errorf("StorageKey(%q) = StorageKey(%q) = %q", name, other, key)
+        }
+        seen[key] = name
+    }
+}
+
+// Writes empty files with the given names to the directory
+func writeFiles(t *testing.T, dir string, names ...string) {
+    for _, name := range names {
+        if err := ioutil.WriteFile(dir + "/" + name, nil, 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+func TestLegacyUserFiles(t *testing.T) {
+    dir, err := ioutil.TempDir("", "data")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    // legacy files named by raw usernames, including names that look like current user files
+    writeFiles(t, dir, "alice", "x.gob", "bob=2egob.gob", USER_DIR_NAME)
+    if err := os.Mkdir(dir + "/blobs", os.ModePerm); err != nil {
+        t.Fatal(err)
+    }
+    writeFiles(t, dir + "/blobs", "abc")
+
+    if err := MakeUserDir(dir); err != nil {
+        t.Fatal(err)
+    }
+    if info, err := os.Stat(dir + "/" + USER_DIR_NAME); err != nil || !info.IsDir() {
+        t.Fatalf("user directory not created: %v", err)
+    }
+    writeFiles(t, dir + "/" + USER_DIR_NAME, StorageKey("carol") + USER_FILE_EXT, StorageKey("x.gob") + USER_FILE_EXT)
+
+    paths, err := LegacyUserFiles(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var got []string
+    for _, path := range paths {
+        got = append(got, path[len(dir) + 1:])
+        if strings.HasPrefix(path, dir + "/" + LEGACY_DIR_PREFIX) {
+            got[len(got) - 1] = LEGACY_DIR_PREFIX + "/" + path[strings.LastIndex(path, "/") + 1:]
+        }
+    }
+    sort.Strings(got)
+    want := []string{LEGACY_DIR_PREFIX + "/" + USER_DIR_NAME, "alice", "bob=2egob.gob", "x.gob"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("LegacyUserFiles = %v, want %v", got, want)
+    }
+}
@@ -0,0 +1,134 @@
+package lib
+
+import (
+    "sort"
+    "time"
+    "unicode"
+)
+
+const MAX_TRENDS = 10  // most hashtags and terms listed as trending
+
+// Sliding windows trends can be computed over, keyed by the name used in commands
+var TREND_WINDOWS = map[string]time.Duration{
+    "hour": time.Hour,
+    "day":  24 * time.Hour,
+    "week": 7 * 24 * time.Hour,
+}
+
+// Common words that are never trending terms
+var stopWords = map[string]bool{
+    "the": true, "and": true, "for": true, "you": true, "are": true, "but": true, "not": true,
+    "this": true, "that": true, "with": true, "have": true, "was": true, "just": true, "what": true,
+    "all": true, "can": true, "out": true, "get": true, "its": true, "from": true, "about": true,
+    "they": true, "will": true, "your": true, "has": true, "had": true, "our": true, "who": true,
+}
+
+// Struct to hold a trending hashtag or term
+type Trend struct {
+    Topic    string
+    Count    int  // posts using the topic in the current window
+    Previous int  // posts using the topic in the window before
+    Score    float64
+}
+
+// Struct to hold the trending hashtags and terms of a window
+type Trends struct {
+    Window   string
+    Hashtags []Trend
+    Terms    []Trend
+}
+
+/*
+    Compute trends counts how many public posts of public accounts that are not suspended or deleted used
+    each hashtag and term in the window ending now and in the window before it. Topics rising compared to the previous window score higher than
+    topics that were already popular.
+    Trends are derived from the stored posts only, so any server holding the same posts computes the
+    same trends, which keeps them consistent when a new master takes over.
+*/
+func ComputeTrends(USERS map[string]*UserInfo, window string, now time.Time) Trends {
+    length, ok := TREND_WINDOWS[window]
+    if !ok {
+        window, length = "day", TREND_WINDOWS["day"]
+    }
+    start := now.Add(-length)
+    previousStart := start.Add(-length)
+
+    tags := make(map[string]*Trend)
+    terms := make(map[string]*Trend)
+    count := func(topics map[string]*Trend, topic string, current bool) {
+        trend, ok := topics[topic]
+        if !ok {
+            trend = &Trend{Topic: topic}
+            topics[topic] = trend
+        }
+        if current {
+            trend.Count++
+        } else {
+            trend.Previous++
+        }
+    }
+
+    for _, user := range USERS {
+        user.mut.Lock()
+        if !user.Private && !user.Suspension.Active && user.DeletedAt.IsZero() {
+            // posts are stored oldest first so walk backwards until the windows are passed
+            for i := len(user.Posts) - 1; i >= 0 && user.Posts[i].Stamp.After(previousStart); i-- {
+                post := user.Posts[i]
+                if post.Stamp.After(now) || post.Visibility != VisibilityPublic || post.TakenDown() || post.Held {
+                    continue
+                }
+                current := post.Stamp.After(start)
+                for _, tag := range Hashtags(post.Message) {
+                    count(tags, "#" + tag, current)
+                }
+                seen := make(map[string]bool)
+                for _, term := range Tokenize(post.Message) {
+                    if !seen[term] && isTrendTerm(term) {
+                        seen[term] = true
+                        count(terms, term, current)
+                    }
+                }
+            }
+        }
+        user.mut.Unlock()
+    }
+    return Trends{window, rankTrends(tags), rankTrends(terms)}
+}
+
+// Checks if a search term is meaningful enough to trend
+func isTrendTerm(term string) bool {
+    if len([]rune(term)) < 3 || stopWords[term] {
+        return false
+    }
+    for _, c := range term {
+        if !unicode.IsDigit(c) {
+            return true
+        }
+    }
+    return false
+}
+
+// Scores the topics used in the current window and returns the best ones
+func rankTrends(topics map[string]*Trend) []Trend {
+    result := []Trend{}
+    for _, trend := range topics {
+        if trend.Count == 0 {
+            continue
+        }
+        trend.Score = float64(trend.Count * trend.Count) / float64(trend.Previous + 1)
+        result = append(result, *trend)
+    }
+    sort.Slice(result, func(i, j int) bool {
+        if result[i].Score != result[j].Score {
+            return result[i].Score > result[j].Score
+        }
+        if result[i].Count != result[j].Count {
+            return result[i].Count > result[j].Count
+        }
+        return result[i].Topic < result[j].Topic
+    })
+    if len(result) > MAX_TRENDS {
+        result = result[:MAX_TRENDS]
+    }
+    return result
+}
@@ -0,0 +1,66 @@
+package lib
+
+import (
+    "testing"
+    "time"
+)
+
+func TestComputeTrends(t *testing.T) {
+    now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    ago := func(minutes int) time.Time { return now.Add(-time.Duration(minutes) * time.Minute) }
+    alice, bob := NewUserInfo("alice", ""), NewUserInfo("bob", "")
+    alice.Posts = []Post{
+        {Id: 1, Message: "#tea time", Stamp: ago(110)},
+        {Id: 2, Message: "#tea again", Stamp: ago(100)},
+        {Id: 3, Message: "#tea please", Stamp: ago(90)},
+        {Id: 4, Message: "#tea now", Stamp: ago(80)},
+        {Id: 5, Message: "#golang the go 2026 release", Stamp: ago(30)},
+        {Id: 6, Message: "#tea and #golang", Stamp: ago(20)},
+        {Id: 7, Message: "#hidden", Stamp: ago(10), Visibility: VisibilityFollowers},
+        {Id: 8, Message: "#held", Stamp: ago(10), Held: true},
+        {Id: 9, Message: "#future", Stamp: now.Add(time.Minute)},
+    }
+    bob.Posts = []Post{
+        {Id: 1, Message: "#old", Stamp: ago(200)},
+        {Id: 2, Message: "#GoLang #tea", Stamp: ago(5)},
+        {Id: 3, Message: "#removed", Stamp: ago(5)},
+    }
+    bob.Posts[2].Takedown.Active = true
+    private := NewUserInfo("private", "")
+    private.Private = true
+    private.Posts = []Post{{Id: 1, Message: "#secret", Stamp: ago(5)}}
+    suspended := NewUserInfo("suspended", "")
+    suspended.Suspension.Active = true
+    suspended.Posts = []Post{{Id: 1, Message: "#banned", Stamp: ago(5)}}
+    USERS := map[string]*UserInfo{"alice": alice, "bob": bob, "private": private, "suspended": suspended}
+
+    trends := ComputeTrends(USERS, "hour", now)
+    if trends.Window != "hour" {
+        t.Errorf("window = %q, want hour", trends.Window)
+    }
+    want := []Trend{{"#golang", 3, 0, 9}, {"#tea", 2, 4, 0.8}}
+    if len(trends.Hashtags) != len(want) {
+        t.Fatalf("hashtags = %+v, want %+v", trends.Hashtags, want)
+    }
+    for i := range want {
+        if trends.Hashtags[i] != want[i] {
+            t.Errorf("hashtag %d = %+v, want %+v", i, trends.Hashtags[i], want[i])
+        }
+    }
+    terms := make(map[string]int)
+    for _, trend := range trends.Terms {
+        terms[trend.Topic] = trend.Count
+    }
+    if trends.Terms[0].Topic != "golang" || terms["release"] != 1 {
+        t.Errorf("terms = %+v, want golang first and release counted", trends.Terms)
+    }
+    for _, term := range []string{"the", "go", "2026", "hidden", "held", "secret", "banned"} {
+        if _, ok := terms[term]; ok {
+            t.Errorf("%q is trending", term)
+        }
+    }
+
+    if window := ComputeTrends(USERS, "year", now).Window; window != "day" {
+        t.Errorf("unknown window fell back to %q, want day", window)
+    }
+}
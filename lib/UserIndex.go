@@ -0,0 +1,146 @@
+package lib
+
+import (
+    "sort"
+    "strings"
+    "sync"
+)
+
+const MAX_USER_RESULTS = 20  // most usernames returned by a single user search
+
+const PAGE_SIZE = 20  // number of users shown on each page of a user list
+
+// Struct to hold a user listed in a search result or user list along with how the viewer relates to them
+type UserResult struct {
+    Username       string
+    FollowerCount  int
+    FollowingCount int
+    Following      bool  // the viewer follows this user
+    Requested      bool  // the viewer is waiting for approval to follow this private user
+    Private        bool
+    Blocked        bool  // the viewer has blocked this user
+    Muted          bool  // the viewer has muted this user
+}
+
+// Struct to hold one page of a user's followers or followed users
+type UserPage struct {
+    Owner   UserResult  // user whose followers or followed users are listed
+    Users   []UserResult
+    Page    int  // pages start at 1
+    HasMore bool
+}
+
+// Struct to hold a sorted index of every username for user search
+type UserIndex struct {
+    names []string
+    mut   *sync.RWMutex
+}
+
+// Creates an empty user index
+func NewUserIndex() *UserIndex {
+    return &UserIndex{names: []string{}, mut: &sync.RWMutex{}}
+}
+
+// Adds a username to the index keeping it sorted
+func (index *UserIndex) Add(name string) {
+    index.mut.Lock()
+    defer index.mut.Unlock()
+    i := sort.SearchStrings(index.names, name)
+    if i < len(index.names) && index.names[i] == name {
+        return
+    }
+    index.names = append(index.names, "")
+    copy(index.names[i+1:], index.names[i:])
+    index.names[i] = name
+}
+
+// Removes a username from the index
+func (index *UserIndex) Remove(name string) {
+    index.mut.Lock()
+    defer index.mut.Unlock()
+    i := sort.SearchStrings(index.names, name)
+    if i < len(index.names) && index.names[i] == name {
+        index.names = append(index.names[:i], index.names[i+1:]...)
+    }
+}
+
+// Removes every username from the index
+func (index *UserIndex) Clear() {
+    index.mut.Lock()
+    defer index.mut.Unlock()
+    index.names = []string{}
+}
+
+// Finds usernames matching the query ignoring case, ranked by exact match, then prefix, then substring,
+// then by edit distance for names within a few typos
+func (index *UserIndex) Search(query string) []string {
+    index.mut.RLock()
+    defer index.mut.RUnlock()
+    query = strings.ToLower(strings.TrimSpace(query))
+    if query == "" {
+        return []string{}
+    }
+    maxDistance := len(query) / 3
+    if maxDistance > 2 {
+        maxDistance = 2
+    }
+
+    type match struct {
+        name string
+        rank int
+    }
+    var matches []match
+    for _, name := range index.names {
+        lower := strings.ToLower(name)
+        switch {
+            case lower == query:
+                matches = append(matches, match{name, 0})
+            case strings.HasPrefix(lower, query):
+                matches = append(matches, match{name, 1})
+            case strings.Contains(lower, query):
+                matches = append(matches, match{name, 2})
+            default:
+                if distance := editDistance(lower, query); distance <= maxDistance {
+                    matches = append(matches, match{name, 2 + distance})
+                }
+        }
+    }
+    // names are already sorted so a stable sort keeps ties alphabetical
+    sort.SliceStable(matches, func(i, j int) bool {
+        return matches[i].rank < matches[j].rank
+    })
+
+    result := []string{}
+    for i := 0; i < len(matches) && i < MAX_USER_RESULTS; i++ {
+        result = append(result, matches[i].name)
+    }
+    return result
+}
+
+// Computes the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+    s, t := []rune(a), []rune(b)
+    prev := make([]int, len(t)+1)
+    curr := make([]int, len(t)+1)
+    for j := range prev {
+        prev[j] = j
+    }
+    for i := 1; i <= len(s); i++ {
+        curr[0] = i
+        for j := 1; j <= len(t); j++ {
+            cost := 1
+            if s[i-1] == t[j-1] {
+                cost = 0
+            }
+            curr[j] = prev[j-1] + cost  // substitution
+            if prev[j] + 1 < curr[j] {
+                curr[j] = prev[j] + 1  // deletion
+            }
+            if curr[j-1] + 1 < curr[j] {
+                curr[j] = curr[j-1] + 1  // insertion
+            }
+        }
+        prev, curr = curr, prev
+    }
+    return prev[len(t)]
+}
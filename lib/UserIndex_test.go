@@ -0,0 +1,71 @@
+package lib
+
+import (
+    "fmt"
+    "reflect"
+    "testing"
+)
+
+func TestUserIndexSearch(t *testing.T) {
+    index := NewUserIndex()
+    for _, name := range []string{"bob", "alice", "Alicia", "malice", "alise", "alex", "bobby", "rob", "alice"} {
+        index.Add(name)
+    }
+    tests := []struct {
+        query string
+        want  []string
+    }{
+        {"alice", []string{"alice", "malice", "alise"}},
+        {"ali", []string{"Alicia", "alice", "alise", "malice"}},
+        {"bob", []string{"bob", "bobby", "rob"}},
+        {"  BOB ", []string{"bob", "bobby", "rob"}},
+        {"alicee", []string{"alice", "Alicia", "alise", "malice"}},
+        {"zed", []string{}},
+        {"", []string{}},
+    }
+    for _, test := range tests {
+        if got := index.Search(test.query); !reflect.DeepEqual(got, test.want) {
+            t.Errorf("Search(%q) = %q, want %q", test.query, got, test.want)
+        }
+    }
+
+    index.Remove("bob")
+    index.Remove("nobody")
+    if got := index.Search("bob"); !reflect.DeepEqual(got, []string{"bobby", "rob"}) {
+        t.Errorf("Search(bob) after remove = %q, want [bobby rob]", got)
+    }
+    index.Clear()
+    if got := index.Search("alice"); len(got) != 0 {
+        t.Errorf("cleared index still finds %q", got)
+    }
+}
+
+func TestUserIndexSearchLimit(t *testing.T) {
+    index := NewUserIndex()
+    for i := 0; i < MAX_USER_RESULTS + 5; i++ {
+        index.Add(fmt.Sprintf("user%02d", i))
+    }
+    got := index.Search("user")
+    if len(got) != MAX_USER_RESULTS || got[0] != "user00" {
+        t.Errorf("Search(user) = %q, want the first %d names", got, MAX_USER_RESULTS)
+    }
+}
+
+func TestEditDistance(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want int
+    }{
+        {"", "", 0},
+        {"bob", "bob", 0},
+        {"bob", "rob", 1},
+        {"bob", "bobby", 2},
+        {"kitten", "sitting", 3},
+        {"\u00e9t\u00e9", "ete", 2},
+    }
+    for _, test := range tests {
+        if got := editDistance(test.a, test.b); got != test.want {
+            t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+        }
+    }
+}
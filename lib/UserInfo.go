@@ -3,6 +3,7 @@ package lib
 import (
     "time"
     "container/heap"
+    "sort"
     "sync"
 )
 
@@ -10,9 +11,33 @@ import (
 type UserInfo struct {
     Username   string
     Password   string
+    DisplayName string
+    Bio        string
+    Joined     time.Time
     Following  map[string]bool
     FollowedBy []string
+    Private    bool      // only followers can see the posts, following requires approval
+    FollowRequests []string  // users waiting for approval to follow a private account
+    Blocked    map[string]bool  // users that can no longer follow, mention or message this user
+    Lists      map[string]*List  // curated lists of users keyed by list name
+    Muted      map[string]bool  // users whose posts are hidden from this user's timeline
     Posts      []Post
+    LastPostId int  // id given to the most recent post, ids start at 1
+    Pinned     int  // id of the post featured on the profile, 0 if none
+    Bookmarks  map[string][]PostRef  // private bookmark collections keyed by name, most recent first
+    PollVoters map[int]map[string]bool  // users who voted in each of the user's open polls keyed by post id
+    Retention  time.Duration  // posts older than this are removed, zero keeps them forever
+    Scheduled  []ScheduledPost  // posts waiting for their publish time
+    LastScheduleId int
+    Reports    []Report  // open reports against the user and their posts, oldest first
+    LastReportId int
+    Suspension Sanction  // an active suspension hides the account and stops it from posting
+    Role       int  // RoleUser, RoleModerator or RoleAdmin
+    DeletedAt  time.Time  // when the user deleted the account, zero if it is not deleted
+    PreviousNames []PreviousName  // usernames the user renamed away from, reserved for a while
+    Notifications []Notification
+    Conversations map[string]*Conversation
+    FollowingOnlyMessages bool  // only accept direct messages from users being followed
     mut        *sync.Mutex
 }
 
@@ -22,17 +47,33 @@ func NewUserInfo(username, password string) *UserInfo {
     newUser.Username = username
     newUser.Password = password
     newUser.Following = make(map[string]bool)
+    newUser.Blocked = make(map[string]bool)
+    newUser.Muted = make(map[string]bool)
+    newUser.Lists = make(map[string]*List)
+    newUser.Bookmarks = make(map[string][]PostRef)
+    newUser.PollVoters = make(map[int]map[string]bool)
+    newUser.Conversations = make(map[string]*Conversation)
     newUser.mut = &sync.Mutex{}
     return newUser
 }
 
 // Struct to hold data associated with a user's post
 type Post struct {
+    Id      int  // unique among the poster's posts
     Poster  string
     Message string
     Time    string
     Stamp   time.Time
-    Index   int  //index of post in priority queue
+    Mentions []string  // existing users mentioned with @username
+    ExpiresAt time.Time  // the post is removed after this time, zero if it never expires
+    Attachments []Attachment
+    Poll    Poll  // empty if the post has no poll
+    Visibility int  // VisibilityPublic, VisibilityFollowers or VisibilityMentioned
+    Takedown Sanction  // an active takedown hides the post from everyone but its poster
+    Held    bool      // held for review by the filters, only the poster sees it until an admin approves it
+    HeldFor string    // why the filters held the post
+    Tags    []string  // labels added by the filters
+    Index   int `json:"-"`  //index of post in priority queue
 }
 
 type PriorityQueue []*Post  // typedef of PriorityQueue as slice of Post pointers
@@ -122,6 +163,107 @@ func (user *UserInfo) UnFollow(oldFollow *UserInfo) bool {
     return true
 }
 
+// Current UserInfo asks to follow the private UserInfo passed in parameter
+// Returns false if the user already follows or has already asked to follow
+func (user *UserInfo) RequestFollow(target *UserInfo) bool {
+    user.mut.Lock()
+    target.mut.Lock()
+    defer user.mut.Unlock()
+    defer target.mut.Unlock()
+    if user.Following[target.Username] {
+        return false
+    }
+    for _, name := range target.FollowRequests {
+        if name == user.Username {
+            return false
+        }
+    }
+    target.FollowRequests = append(target.FollowRequests, user.Username)
+    return true
+}
+
+// Current UserInfo withdraws its request to follow the UserInfo passed in parameter
+// Returns false if there was no pending request
+func (user *UserInfo) CancelFollowRequest(target *UserInfo) bool {
+    target.mut.Lock()
+    defer target.mut.Unlock()
+    return target.removeFollowRequest(user.Username)
+}
+
+// Current UserInfo approves or denies the pending follow request of the UserInfo passed in parameter
+// Approving makes the requester a follower, returns false if there was no pending request
+func (user *UserInfo) AnswerFollowRequest(requester *UserInfo, approve bool) bool {
+    user.mut.Lock()
+    requester.mut.Lock()
+    defer user.mut.Unlock()
+    defer requester.mut.Unlock()
+    if !user.removeFollowRequest(requester.Username) {
+        return false
+    }
+    if approve && !requester.Following[user.Username] {
+        user.FollowedBy = append(user.FollowedBy, requester.Username)
+        requester.Following[user.Username] = true
+    }
+    return true
+}
+
+// Removes a username from the pending follow requests, the user mutex must be held
+func (user *UserInfo) removeFollowRequest(name string) bool {
+    for i := range user.FollowRequests {
+        if user.FollowRequests[i] == name {
+            user.FollowRequests = append(user.FollowRequests[:i], user.FollowRequests[i+1:]...)
+            return true
+        }
+    }
+    return false
+}
+
+// Checks if current UserInfo has a pending request to follow the UserInfo passed in parameter
+func (user *UserInfo) HasRequested(target *UserInfo) bool {
+    target.mut.Lock()
+    defer target.mut.Unlock()
+    for _, name := range target.FollowRequests {
+        if name == user.Username {
+            return true
+        }
+    }
+    return false
+}
+
+// Returns a copy of the pending follow requests of the current UserInfo
+func (user *UserInfo) GetFollowRequests() []string {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return append([]string{}, user.FollowRequests...)
+}
+
+// Sets whether the account is private
+func (user *UserInfo) SetPrivate(private bool) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    user.Private = private
+}
+
+// Checks if the account is private
+func (user *UserInfo) IsPrivate() bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return user.Private
+}
+
+// Checks if the posts of the current UserInfo can be seen by the viewer
+// Posts of private accounts are only visible to the account itself and its followers,
+// posts are never visible to users the account has blocked and suspended or deleted accounts are hidden from everyone
+func (user *UserInfo) VisibleTo(viewer *UserInfo) bool {
+    if user == viewer {
+        return true
+    }
+    if user.HasBlocked(viewer.Username) || user.IsHidden() {
+        return false
+    }
+    return !user.IsPrivate() || viewer.IsFollowing(user)
+}
+
 // Checks if current UserInfo is following the UserInfo passed in parameter
 func (user *UserInfo) IsFollowing(other *UserInfo) bool {
     user.mut.Lock()
@@ -137,35 +279,161 @@ func (user *UserInfo) IsFollowing(other *UserInfo) bool {
 }
 
 
-// Creates a Post appended to UserInfo's Posts member
-func (user *UserInfo) WritePost(msg string){
+// Returns the number of users following the current UserInfo
+func (user *UserInfo) FollowerCount() int {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return len(user.FollowedBy)
+}
+
+// Returns the number of users the current UserInfo follows
+func (user *UserInfo) FollowingCount() int {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    return len(user.Following)
+}
+
+// Returns the usernames following the current UserInfo in alphabetical order
+func (user *UserInfo) GetFollowers() []string {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    followers := append([]string{}, user.FollowedBy...)
+    sort.Strings(followers)
+    return followers
+}
+
+// Returns the usernames the current UserInfo follows in alphabetical order
+func (user *UserInfo) GetFollowing() []string {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    following := []string{}
+    for name := range user.Following {
+        following = append(following, name)
+    }
+    sort.Strings(following)
+    return following
+}
+
+// Creates a Post from the draft appended to UserInfo's Posts member and returns a copy of it
+// The draft holds the message, the time the post is stamped with and any attachments, poll or expiry time
+// Every @username mention of an existing user is stored on the post and the mentioned user is notified,
+// the users that were notified are returned so their files can be rewritten
+// Users that have blocked the poster or that the poster has blocked are not mentioned, mentioned users
+// the post's visibility hides it from are not notified
+func (user *UserInfo) WritePost(draft Post, USERS map[string]*UserInfo) (Post, []string) {
+    var mentions []string
+    for _, name := range ParseMentions(draft.Message, USERS) {
+        if name == user.Username || !EitherBlocked(user, USERS[name]) {
+            mentions = append(mentions, name)
+        }
+    }
     user.mut.Lock()
-    newPost := Post{Poster: user.Username, Message: msg, Time: time.Now().Format(time.RFC1123)[0:len(time.RFC1123)-4], Stamp: time.Now()}
-    user.Posts = append(user.Posts, newPost)
+    user.LastPostId++
+    draft.Id = user.LastPostId
+    draft.Poster = user.Username
+    draft.Time = draft.Stamp.Format(time.RFC1123)[0:len(time.RFC1123)-4]
+    draft.Mentions = mentions
+    user.Posts = append(user.Posts, draft)
     user.mut.Unlock()
+
+    var notified []string
+    for _, name := range mentions {
+        if name == user.Username || !draft.VisibleTo(name, USERS[name].IsFollowing(user)) {
+            continue
+        }
+        USERS[name].Notify(NotifyMention, user.Username, draft.Message, draft.Stamp)
+        notified = append(notified, name)
+    }
+    return draft, notified
 }
 
-// Creates a PriorityQueue implemented with a heap to pull all of the posts and return a slice with
-// the posts in order (includes the current user's posts)
-func (user *UserInfo) GetAllChirps(USERS map[string]*UserInfo) []Post {
+// Gives ids to the posts of a user stored before posts had ids
+func (user *UserInfo) NumberPosts() {
     user.mut.Lock()
     defer user.mut.Unlock()
-    var result = []Post{}
+    if user.LastPostId != 0 {
+        return
+    }
+    for i := range user.Posts {
+        user.LastPostId++
+        user.Posts[i].Id = user.LastPostId
+    }
+}
 
-    var allChirps PriorityQueue
-    heap.Init(&allChirps)  // initializes the PriorityQueue as a heap
+// Returns a copy of the user's post with the given id
+func (user *UserInfo) GetPost(id int) (Post, bool) {
+    user.mut.Lock()
+    defer user.mut.Unlock()
+    for _, post := range user.Posts {
+        if post.Id == id {
+            return post, true
+        }
+    }
+    return Post{}, false
+}
+
+// Removes the user's post with the given id, returns false if there is no such post
+// The post is unpinned if it was pinned and open reports against it are closed
+func (user *UserInfo) DeletePost(id int) bool {
+    user.mut.Lock()
+    defer user.mut.Unlock()
     for i := range user.Posts {
-        heap.Push(&allChirps, &(user.Posts[i]))  // uses the Push method defined above
+        if user.Posts[i].Id == id {
+            user.Posts = append(user.Posts[:i], user.Posts[i+1:]...)
+            if user.Pinned == id {
+                user.Pinned = 0
+            }
+            delete(user.PollVoters, id)
+            user.resolveReports(id)
+            return true
+        }
     }
+    return false
+}
+
+// Gets the posts of the current user and every user they follow in order, newest first
+// (includes the current user's posts, leaves out muted users, expired posts and posts hidden from the user)
+func (user *UserInfo) GetAllChirps(USERS map[string]*UserInfo) []Post {
+    user.mut.Lock()
+    posters := []*UserInfo{user}
     for followed := range user.Following {
-        USERS[followed].mut.Lock()
-        for i := range USERS[followed].Posts {
-            heap.Push(&allChirps, &(USERS[followed].Posts[i]))  // uses the Push method defined above
+        if !user.Muted[followed] {
+            posters = append(posters, USERS[followed])
         }
-        USERS[followed].mut.Unlock()
     }
+    user.mut.Unlock()
+    return mergeChirps(user, posters)
+}
+
+// Creates a PriorityQueue implemented with a heap to pull all of the posts of the given users and return
+// a slice with the posts in order, newest first, leaving out expired posts, posts hidden from the viewer
+// by their visibility or by moderators and the posts of suspended or deleted posters, the viewer must already be
+// allowed to see the posters' accounts
+func mergeChirps(viewer *UserInfo, posters []*UserInfo) []Post {
+    var result = []Post{}
+
+    var allChirps PriorityQueue
+    heap.Init(&allChirps)  // initializes the PriorityQueue as a heap
+    for _, poster := range posters {
+        if poster != viewer && poster.IsHidden() {
+            continue
+        }
+        follows := poster != viewer && viewer.IsFollowing(poster)
+        poster.mut.Lock()
+        for i := range poster.Posts {
+            post := poster.Posts[i]  // copied so the heap never points into a slice changed after unlocking
+            if post.VisibleTo(viewer.Username, follows) {
+                heap.Push(&allChirps, &post)  // uses the Push method defined above
+            }
+        }
+        poster.mut.Unlock()
+    }
+    now := time.Now()
     for allChirps.Len() > 0 {  // uses the Len method defined above
-        result = append(result, *heap.Pop(&allChirps).(*Post))  // appends the result of Pop (defined above) to the resulting slice
+        post := heap.Pop(&allChirps).(*Post)
+        if !post.Expired(now) {  // expired posts may not have been swept yet
+            result = append(result, *post)
+        }
     }
     return result
 }
@@ -0,0 +1,77 @@
+package lib
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestFollowLists(t *testing.T) {
+    USERS := map[string]*UserInfo{}
+    for _, name := range []string{"alice", "bob", "carol", "dave"} {
+        USERS[name] = NewUserInfo(name, "")
+    }
+    alice := USERS["alice"]
+    for _, name := range []string{"dave", "bob", "carol"} {
+        if !USERS[name].Follow(alice) {
+            t.Errorf("%s could not follow alice", name)
+        }
+    }
+    if USERS["bob"].Follow(alice) {
+        t.Errorf("bob followed alice twice")
+    }
+    alice.Follow(USERS["carol"])
+    alice.Follow(USERS["bob"])
+
+    if got := alice.GetFollowers(); !reflect.DeepEqual(got, []string{"bob", "carol", "dave"}) {
+        t.Errorf("followers = %q, want sorted [bob carol dave]", got)
+    }
+    if got := alice.GetFollowing(); !reflect.DeepEqual(got, []string{"bob", "carol"}) {
+        t.Errorf("following = %q, want sorted [bob carol]", got)
+    }
+    if alice.FollowerCount() != 3 || alice.FollowingCount() != 2 {
+        t.Errorf("counts = %d followers, %d following, want 3 and 2", alice.FollowerCount(), alice.FollowingCount())
+    }
+
+    if !USERS["carol"].UnFollow(alice) || USERS["carol"].UnFollow(alice) {
+        t.Errorf("carol should unfollow alice exactly once")
+    }
+    if got := alice.GetFollowers(); !reflect.DeepEqual(got, []string{"bob", "dave"}) {
+        t.Errorf("followers after unfollow = %q, want [bob dave]", got)
+    }
+    if USERS["carol"].IsFollowing(alice) || !USERS["bob"].IsFollowing(alice) {
+        t.Errorf("IsFollowing does not match the follow lists")
+    }
+}
+
+func TestFollowRequests(t *testing.T) {
+    alice, bob, carol := NewUserInfo("alice", ""), NewUserInfo("bob", ""), NewUserInfo("carol", "")
+    alice.SetPrivate(true)
+    if !bob.VisibleTo(alice) || alice.VisibleTo(bob) {
+        t.Errorf("a private account is visible before the follow request is approved")
+    }
+
+    if !bob.RequestFollow(alice) || bob.RequestFollow(alice) || !carol.RequestFollow(alice) {
+        t.Errorf("each user should request to follow alice exactly once")
+    }
+    if !bob.HasRequested(alice) || !reflect.DeepEqual(alice.GetFollowRequests(), []string{"bob", "carol"}) {
+        t.Errorf("requests = %q, want [bob carol]", alice.GetFollowRequests())
+    }
+
+    if !alice.AnswerFollowRequest(bob, true) || alice.AnswerFollowRequest(bob, true) {
+        t.Errorf("bob's request should be answered exactly once")
+    }
+    if !bob.IsFollowing(alice) || !alice.VisibleTo(bob) || bob.HasRequested(alice) {
+        t.Errorf("approved request did not make bob a follower")
+    }
+    if !alice.AnswerFollowRequest(carol, false) || carol.IsFollowing(alice) || alice.VisibleTo(carol) {
+        t.Errorf("denied request made carol a follower")
+    }
+
+    carol.RequestFollow(alice)
+    if !carol.CancelFollowRequest(alice) || carol.CancelFollowRequest(alice) || len(alice.GetFollowRequests()) != 0 {
+        t.Errorf("carol should cancel the request exactly once, requests = %q", alice.GetFollowRequests())
+    }
+    if bob.RequestFollow(alice) {
+        t.Errorf("a follower could ask to follow again")
+    }
+}
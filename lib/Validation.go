@@ -0,0 +1,113 @@
+package lib
+
+import (
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+const MAX_POST_LENGTH = 100  // longest post in characters (Unicode code points), after normalizing
+const MAX_MESSAGE_LENGTH = 500  // longest direct message in characters, after normalizing
+const MIN_USERNAME_LENGTH = 3
+const MAX_USERNAME_LENGTH = 20
+
+// Usernames nobody can sign up or rename to, compared ignoring case, so no account can pass for the service
+var RESERVED_USERNAMES = map[string]bool{
+    "admin": true, "administrator": true, "moderator": true, "mod": true, "root": true, "system": true,
+    "support": true, "help": true, "security": true, "staff": true, "chirp": true, "official": true,
+    "null": true, "nil": true, "undefined": true, "everyone": true,
+}
+
+/*
+    Normalize text cleans up text written by a user before it is stored:
+    invalid UTF-8 is replaced, the text is put in Unicode normalization form C so composed and decomposed
+    spellings of a character are stored the same way, line endings become "\n", control characters other than
+    newlines are dropped, runs of spaces and tabs become a single space, spaces at the end of lines and blank
+    lines beyond one in a row are removed, and the text is trimmed.
+*/
+func NormalizeText(text string) string {
+    text = strings.ToValidUTF8(text, string(utf8.RuneError))
+    text = NFC(text)
+    text = strings.ReplaceAll(text, "\r\n", "\n")
+    text = strings.ReplaceAll(text, "\r", "\n")
+
+    var lines []string
+    for _, line := range strings.Split(text, "\n") {
+        var builder strings.Builder
+        space := false
+        for _, r := range line {
+            if unicode.IsSpace(r) {
+                space = true
+                continue
+            }
+            if unicode.IsControl(r) {
+                continue
+            }
+            if space && builder.Len() > 0 {
+                builder.WriteByte(' ')
+            }
+            space = false
+            builder.WriteRune(r)
+        }
+        lines = append(lines, builder.String())
+    }
+
+    var kept []string
+    blank := 0
+    for _, line := range lines {
+        if line == "" {
+            blank++
+            if blank > 1 {
+                continue
+            }
+        } else {
+            blank = 0
+        }
+        kept = append(kept, line)
+    }
+    return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// Normalizes a post's message and checks its length, every path that writes a post goes through here
+// Returns the normalized message and StatusAccepted, StatusEmptyPost or StatusPostTooLong
+func ValidatePost(message string) (string, int) {
+    message = NormalizeText(message)
+    if message == "" {
+        return message, StatusEmptyPost
+    }
+    if utf8.RuneCountInString(message) > MAX_POST_LENGTH {
+        return message, StatusPostTooLong
+    }
+    return message, StatusAccepted
+}
+
+// Normalizes a direct message and checks its length the way ValidatePost does for posts
+// Returns the normalized message and StatusAccepted, StatusEmptyPost or StatusMessageTooLong
+func ValidateMessage(message string) (string, int) {
+    message = NormalizeText(message)
+    if message == "" {
+        return message, StatusEmptyPost
+    }
+    if utf8.RuneCountInString(message) > MAX_MESSAGE_LENGTH {
+        return message, StatusMessageTooLong
+    }
+    return message, StatusAccepted
+}
+
+// Checks a username against the username policy used for signups and renames
+// Usernames are 3 to 20 ASCII letters, digits or underscores and cannot be a reserved name
+// Returns StatusAccepted or StatusInvalidUsername
+func ValidateUsername(username string) int {
+    if len(username) < MIN_USERNAME_LENGTH || len(username) > MAX_USERNAME_LENGTH {
+        return StatusInvalidUsername
+    }
+    for _, r := range username {
+        if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+            return StatusInvalidUsername
+        }
+    }
+    if RESERVED_USERNAMES[strings.ToLower(username)] {
+        return StatusInvalidUsername
+    }
+    return StatusAccepted
+}
@@ -0,0 +1,97 @@
+package lib
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+    tests := []struct {
+        name, text, want string
+    }{
+        {"empty", "", ""},
+        {"only spaces", " \t \n ", ""},
+        {"decomposed accent", "cafe\u0301", "caf\u00e9"},
+        {"composed accent", "caf\u00e9", "caf\u00e9"},
+        {"line endings", "a\r\nb\rc", "a\nb\nc"},
+        {"spaces and tabs", "  a \t  b  ", "a b"},
+        {"blank lines", "a\n\n\n\nb", "a\n\nb"},
+        {"control characters", "a\x00b\x07c", "abc"},
+        {"invalid utf-8", "a\xffb", "a\ufffdb"},
+    }
+    for _, test := range tests {
+        if got := NormalizeText(test.text); got != test.want {
+            t.Errorf("%s: NormalizeText(%q) = %q, want %q", test.name, test.text, got, test.want)
+        }
+    }
+}
+
+func TestValidatePost(t *testing.T) {
+    tests := []struct {
+        name, message, want string
+        status              int
+    }{
+        {"empty", "", "", StatusEmptyPost},
+        {"whitespace", " \n\t ", "", StatusEmptyPost},
+        {"control characters only", "\x00\x01", "", StatusEmptyPost},
+        {"short", " hello ", "hello", StatusAccepted},
+        {"at the limit", strings.Repeat("a", MAX_POST_LENGTH), strings.Repeat("a", MAX_POST_LENGTH), StatusAccepted},
+        {"over the limit", strings.Repeat("a", MAX_POST_LENGTH + 1), strings.Repeat("a", MAX_POST_LENGTH + 1), StatusPostTooLong},
+        {"multibyte at the limit", strings.Repeat("\u00e9", MAX_POST_LENGTH), strings.Repeat("\u00e9", MAX_POST_LENGTH), StatusAccepted},
+        // decomposed accents take two code points each until they are composed
+        {"decomposed at the limit", strings.Repeat("e\u0301", MAX_POST_LENGTH), strings.Repeat("\u00e9", MAX_POST_LENGTH), StatusAccepted},
+    }
+    for _, test := range tests {
+        got, status := ValidatePost(test.message)
+        if got != test.want || status != test.status {
+            t.Errorf("%s: ValidatePost(%q) = %q, %d, want %q, %d", test.name, test.message, got, status, test.want, test.status)
+        }
+    }
+}
+
+func TestValidateMessage(t *testing.T) {
+    tests := []struct {
+        name, message, want string
+        status              int
+    }{
+        {"empty", "", "", StatusEmptyPost},
+        {"whitespace", " \r\n ", "", StatusEmptyPost},
+        {"short", "hi\r\nthere ", "hi\nthere", StatusAccepted},
+        {"longer than a post", strings.Repeat("a", MAX_POST_LENGTH + 1), strings.Repeat("a", MAX_POST_LENGTH + 1), StatusAccepted},
+        {"at the limit", strings.Repeat("a", MAX_MESSAGE_LENGTH), strings.Repeat("a", MAX_MESSAGE_LENGTH), StatusAccepted},
+        {"over the limit", strings.Repeat("a", MAX_MESSAGE_LENGTH + 1), strings.Repeat("a", MAX_MESSAGE_LENGTH + 1), StatusMessageTooLong},
+    }
+    for _, test := range tests {
+        got, status := ValidateMessage(test.message)
+        if got != test.want || status != test.status {
+            t.Errorf("%s: ValidateMessage(%q) = %q, %d, want %q, %d", test.name, test.message, got, status, test.want, test.status)
+        }
+    }
+}
+
+func TestValidateUsername(t *testing.T) {
+    tests := []struct {
+        username string
+        status   int
+    }{
+        {"bob", StatusAccepted},
+        {"Alice_99", StatusAccepted},
+        {strings.Repeat("a", MAX_USERNAME_LENGTH), StatusAccepted},
+        {"", StatusInvalidUsername},
+        {"ab", StatusInvalidUsername},
+        {strings.Repeat("a", MAX_USERNAME_LENGTH + 1), StatusInvalidUsername},
+        {"x.gob", StatusInvalidUsername},
+        {"a b", StatusInvalidUsername},
+        {"../etc", StatusInvalidUsername},
+        {"bob-1", StatusInvalidUsername},
+        {"café", StatusInvalidUsername},
+        {"admin", StatusInvalidUsername},
+        {"Admin", StatusInvalidUsername},
+        {"SUPPORT", StatusInvalidUsername},
+    }
+    for _, test := range tests {
+        if status := ValidateUsername(test.username); status != test.status {
+            t.Errorf("ValidateUsername(%q) = %d, want %d", test.username, status, test.status)
+        }
+    }
+}
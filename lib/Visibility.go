@@ -0,0 +1,62 @@
+package lib
+
+const (
+    VisibilityPublic    = iota  // anyone who can see the account can see the post
+    VisibilityFollowers         // only followers of the poster can see the post
+    VisibilityMentioned         // only users mentioned in the post can see it
+)
+
+// Checks if a visibility value is one of the known ones
+func ValidVisibility(visibility int) bool {
+    return visibility >= VisibilityPublic && visibility <= VisibilityMentioned
+}
+
+// Checks if the viewer may see the post given whether the viewer follows the poster
+// Posts taken down by moderators or held for review are only seen by their poster
+// Account rules, private accounts and blocking, are checked separately with UserInfo.VisibleTo
+func (post Post) VisibleTo(viewer string, follows bool) bool {
+    if viewer == post.Poster {
+        return true
+    }
+    if post.TakenDown() || post.Held {
+        return false
+    }
+    switch post.Visibility {
+        case VisibilityFollowers:
+            return follows
+        case VisibilityMentioned:
+            for _, name := range post.Mentions {
+                if name == viewer {
+                    return true
+                }
+            }
+            return false
+    }
+    return true
+}
+
+// Returns a short description of who can see the post, empty for public posts
+func (post Post) Audience() string {
+    switch post.Visibility {
+        case VisibilityFollowers:
+            return "Followers only"
+        case VisibilityMentioned:
+            return "Mentioned only"
+    }
+    return ""
+}
+
+/*
+    Can see post checks everything deciding whether the viewer may see a post of the current user:
+    the account must be visible to the viewer and not suspended, neither may have blocked the other and the post's own
+    visibility must allow the viewer.
+*/
+func (user *UserInfo) CanSeePost(viewer *UserInfo, post Post) bool {
+    if user == viewer {
+        return true
+    }
+    if !user.VisibleTo(viewer) || EitherBlocked(user, viewer) {
+        return false
+    }
+    return post.VisibleTo(viewer.Username, viewer.IsFollowing(user))
+}
@@ -0,0 +1,89 @@
+package lib
+
+import (
+    "testing"
+)
+
+func TestPostVisibleTo(t *testing.T) {
+    public := Post{Poster: "alice"}
+    followers := Post{Poster: "alice", Visibility: VisibilityFollowers}
+    mentioned := Post{Poster: "alice", Visibility: VisibilityMentioned, Mentions: []string{"bob"}}
+    held := Post{Poster: "alice", Held: true}
+    takenDown := Post{Poster: "alice", Takedown: Sanction{Active: true}}
+    tests := []struct {
+        name    string
+        post    Post
+        viewer  string
+        follows bool
+        want    bool
+    }{
+        {"public", public, "carol", false, true},
+        {"followers only to a follower", followers, "carol", true, true},
+        {"followers only to a stranger", followers, "carol", false, false},
+        {"followers only to the poster", followers, "alice", false, true},
+        {"mentioned only to the mentioned user", mentioned, "bob", false, true},
+        {"mentioned only to a follower", mentioned, "carol", true, false},
+        {"held", held, "carol", true, false},
+        {"held to the poster", held, "alice", false, true},
+        {"taken down", takenDown, "carol", true, false},
+        {"taken down to the poster", takenDown, "alice", false, true},
+    }
+    for _, test := range tests {
+        if got := test.post.VisibleTo(test.viewer, test.follows); got != test.want {
+            t.Errorf("%s: VisibleTo(%q, %v) = %v, want %v", test.name, test.viewer, test.follows, got, test.want)
+        }
+    }
+}
+
+func TestAudience(t *testing.T) {
+    tests := []struct {
+        visibility int
+        want       string
+    }{
+        {VisibilityPublic, ""},
+        {VisibilityFollowers, "Followers only"},
+        {VisibilityMentioned, "Mentioned only"},
+    }
+    for _, test := range tests {
+        if got := (Post{Visibility: test.visibility}).Audience(); got != test.want {
+            t.Errorf("Audience() of visibility %d = %q, want %q", test.visibility, got, test.want)
+        }
+        if !ValidVisibility(test.visibility) {
+            t.Errorf("ValidVisibility(%d) = false", test.visibility)
+        }
+    }
+    if ValidVisibility(-1) || ValidVisibility(VisibilityMentioned + 1) {
+        t.Errorf("ValidVisibility accepts unknown values")
+    }
+}
+
+func TestCanSeePost(t *testing.T) {
+    tests := []struct {
+        name  string
+        setup func(poster, viewer *UserInfo)
+        post  Post
+        want  bool
+    }{
+        {"public", func(poster, viewer *UserInfo) {}, Post{}, true},
+        {"private account", func(poster, viewer *UserInfo) { poster.Private = true }, Post{}, false},
+        {"private account followed", func(poster, viewer *UserInfo) { poster.Private = true; viewer.Follow(poster) }, Post{}, true},
+        {"poster blocked viewer", func(poster, viewer *UserInfo) { poster.Blocked["bob"] = true }, Post{}, false},
+        {"viewer blocked poster", func(poster, viewer *UserInfo) { viewer.Blocked["alice"] = true }, Post{}, false},
+        {"suspended poster", func(poster, viewer *UserInfo) { poster.Suspend("spam") }, Post{}, false},
+        {"followers only", func(poster, viewer *UserInfo) {}, Post{Visibility: VisibilityFollowers}, false},
+        {"followers only followed", func(poster, viewer *UserInfo) { viewer.Follow(poster) }, Post{Visibility: VisibilityFollowers}, true},
+        {"mentioned only", func(poster, viewer *UserInfo) {}, Post{Visibility: VisibilityMentioned, Mentions: []string{"bob"}}, true},
+        {"mentioned only but blocked", func(poster, viewer *UserInfo) { poster.Blocked["bob"] = true }, Post{Visibility: VisibilityMentioned, Mentions: []string{"bob"}}, false},
+    }
+    for _, test := range tests {
+        poster, viewer := NewUserInfo("alice", ""), NewUserInfo("bob", "")
+        test.setup(poster, viewer)
+        test.post.Poster = "alice"
+        if got := poster.CanSeePost(viewer, test.post); got != test.want {
+            t.Errorf("%s: CanSeePost = %v, want %v", test.name, got, test.want)
+        }
+        if !poster.CanSeePost(poster, test.post) {
+            t.Errorf("%s: the poster cannot see their own post", test.name)
+        }
+    }
+}
@@ -3,35 +3,107 @@ package main
 import (
     . "../../lib"
     "encoding/gob"
+    "flag"
     "io/ioutil"
     "log"
     "net"
     "os"
+    "path/filepath"
     "strconv"
+    "strings"
     "sync"
     "time"
+    "unicode/utf8"
 )
 
 var USERS_LOCK = &sync.RWMutex{}    // Lock for user map
 var USERS = map[string]*UserInfo{}  // Map of all users
 var LOG map[int]*log.Logger         // Logger for backend
+var INDEX = NewChirpIndex()         // Inverted index over the text of every chirp
+var USER_INDEX = NewUserIndex()     // Sorted index of every username
+var FILTERS = NewFilterChain()      // Filters run on every new chirp and direct message
+var EXPORTS_LOCK = &sync.Mutex{}    // Lock for export map
+var EXPORTS = map[string]ExportStatus{}  // State of the data exports asked for since the server started
+var RETENTION = flag.Duration("retention", 0, "remove posts older than this from every account, 0 keeps them forever")
+var GRACE = flag.Duration("deletion-grace", 30 * 24 * time.Hour, "how long a deleted account can be restored before it is purged (the master's setting applies)")
+var RESERVE = flag.Duration("name-reserve", 30 * 24 * time.Hour, "how long a username stays reserved after its owner renames away from it, every server needs the same setting")
+var ADMINS = flag.String("admins", "", "comma separated usernames made admins when their account is loaded or created, every server needs the same list")
+
+const SWEEP_INTERVAL = 10 * time.Second  // how often the master removes expired posts
 
 func main() {
+    flag.Parse()
     if _, err := os.Stat("../../log"); os.IsNotExist(err) {
         os.Mkdir("../../log", os.ModePerm)
     }
-    if _, err := os.Stat("../../data"); os.IsNotExist(err) {
-        os.Mkdir("../../data", os.ModePerm)
+    if _, err := os.Stat(DATA_DIR); os.IsNotExist(err) {
+        os.Mkdir(DATA_DIR, os.ModePerm)
+    }
+    if err := MakeUserDir(DATA_DIR); err != nil {
+        log.Println("Unable to create the user directory", err)
+    }
+    if _, err := os.Stat("../../config"); os.IsNotExist(err) {
+        os.Mkdir("../../config", os.ModePerm)
     }
     LOG = InitLog("../../log/backend.log")
 
     // Register for encoding and decoding struct values within data types
     gob.Register([]Post{})
+    gob.Register([]string{})
+    gob.Register([]Notification{})
+    gob.Register([]UserResult{})
+    gob.Register([]Recommendation{})
+    gob.Register(Trends{})
+    gob.Register(UserPage{})
+    gob.Register(Profile{})
+    gob.Register(Inbox{})
     gob.Register(struct{Username, Password string}{})
     gob.Register(struct{Username1, Username2 string}{})
     gob.Register(struct{Searcher, Target string}{})
-    gob.Register(struct{Username, Post string}{})
+    gob.Register(struct{Username, Post string; Lifetime time.Duration; Attachments []Attachment; Visibility int}{})
     gob.Register(struct{Id int; Serverlist []int}{})
+    gob.Register(struct{Sender string; Recipients []string; Message string}{})
+    gob.Register(struct{Username string; FollowingOnly bool}{})
+    gob.Register(struct{Username string; Id int}{})
+    gob.Register(struct{Searcher, Query string}{})
+    gob.Register(struct{Viewer, Target string; Page int}{})
+    gob.Register(struct{Username, DisplayName, Bio string}{})
+    gob.Register(struct{Username string; Private bool}{})
+    gob.Register(struct{Username, Requester string; Approve bool}{})
+    gob.Register([]ScheduledPost{})
+    gob.Register(struct{Username, Post string; PublishAt time.Time}{})
+    gob.Register(struct{Username string; Id int; Stamp time.Time}{})
+    gob.Register(struct{Username string; Retention time.Duration}{})
+    gob.Register(struct{Username string; Ids []int}{})
+    gob.Register(Attachment{})
+    gob.Register([]byte{})
+    gob.Register(struct{Viewer, Hash string}{})
+    gob.Register(struct{Username, Name string; Data []byte}{})
+    gob.Register([]List{})
+    gob.Register(ListTimeline{})
+    gob.Register(struct{Username, Name string}{})
+    gob.Register(struct{Username, Name string; Private bool}{})
+    gob.Register(struct{Username, Name, Member string}{})
+    gob.Register(struct{Viewer, Owner string}{})
+    gob.Register(struct{Viewer, Owner, Name string}{})
+    gob.Register(Bookmarks{})
+    gob.Register(struct{Username, Collection, Poster string; Id int}{})
+    gob.Register(struct{Username, Collection string}{})
+    gob.Register(struct{Username, Post string; Options []string; Duration time.Duration}{})
+    gob.Register(struct{Username, Poster string; Id, Option int}{})
+    gob.Register(struct{Username string; Id int; Counts []int}{})
+    gob.Register([]ModerationItem{})
+    gob.Register(Standing{})
+    gob.Register([]Account{})
+    gob.Register(ExportStatus{})
+    gob.Register(struct{Reporter, Target string; Id int; Reason string}{})
+    gob.Register(struct{Admin, Target string; Id, Action int; Reason string}{})
+    gob.Register(struct{Username string; Id int; Message string}{})
+    gob.Register(struct{Admin, Rules string}{})
+    gob.Register(struct{Admin, Query string}{})
+    gob.Register(struct{Admin, Target string; Role int}{})
+    gob.Register(struct{Username, NewName string}{})
+    gob.Register(Stamped{})
 
     replica := NewReplica()
 
@@ -47,7 +119,11 @@ func main() {
             USERS[uInfo.Username] = &uInfo
         }
     }
+    buildIndexes()
+    loadFilters()
     infoChannel <- 0  // Make replica wait for load users to run
+    go runScheduler(&replica)
+    go runSweeper(&replica)
 
     addr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:" + strconv.Itoa(replica.Port))
     if err != nil {
@@ -67,6 +143,8 @@ func main() {
             writeUser(&uInfo)
             USERS[uInfo.Username] = &uInfo
         }
+        buildIndexes()
+        loadFilters()
         infoChannel <- 0
         if replica.IsMaster {
             LOG[ERROR].Println("double resolve to master, unable to listen", err)
@@ -121,6 +199,8 @@ func main() {
                                 USERS[uInfo.Username] = &uInfo
                             }
                         }
+                        buildIndexes()
+                        loadFilters()
                         infoChannel <- 0  // make replica wait for load users to run
                     } else {
                         replica.StartNewMaster(&USERS, USERS_LOCK)
@@ -142,6 +222,7 @@ func main() {
             continue
         }
         if replica.IsMaster {
+            request = request.Stamp(time.Now())
             replica.PropagateRequest(request)
         }
         go runCommand(conn, request, &replica)
@@ -149,63 +230,333 @@ func main() {
 }
 
 /*
-    Load Users reads encoded gob files from the data directory and fills the USERS map with
+    Load Users reads encoded gob files from the user directory and fills the USERS map with
     the corresponding UserInfo data.
-    Each file in the data directory represents a single user, the file is opened, decoded and stored
+    Each file in the user directory represents a single user, the file is opened, decoded and stored
     in the USERS map.
+    User files written by older versions directly in the data directory are migrated first: the user is
+    written to its keyed file in the user directory and the old file is removed. If the keyed file already
+    exists it is newer, so the old file is only removed.
 */
 func loadUsers() {
-    users, err := ioutil.ReadDir("../../data")
+    legacy, err := LegacyUserFiles(DATA_DIR)
     if err != nil {
         LOG[ERROR].Println("Unable to read the data directory", err)
         panic(err)
     }
+    for _, path := range legacy {
+        if uInfo, ok := readUser(path); ok {
+            migrateUserFile(path, uInfo)
+        }
+    }
+
+    users, err := ioutil.ReadDir(USER_DIR)
+    if err != nil {
+        LOG[ERROR].Println("Unable to read the user directory", err)
+        panic(err)
+    }
     USERS_LOCK.Lock()
     defer USERS_LOCK.Unlock()
     for _, user := range users {
         if !user.IsDir() {
-            file, err := os.Open("../../data/" + user.Name())
-            if err != nil {
-                LOG[WARNING].Println("Unable to open file", user.Name(), ", skipping", err)
+            uInfo, ok := readUser(USER_DIR + "/" + user.Name())
+            if !ok {
                 continue
             }
-            decoder := gob.NewDecoder(file)
-            uInfo := NewUserInfo("","")
-            err = decoder.Decode(uInfo)
-            if err != nil {
-                LOG[ERROR].Println(StatusText(StatusDecodeError), err)
-                file.Close()
-                continue
+            uInfo.NumberPosts()
+            if isBootstrapAdmin(uInfo.Username) && !uInfo.HasRole(RoleAdmin) {
+                uInfo.SetRole(RoleAdmin)
+                writeUser(uInfo)
+                LOG[INFO].Println("Made", uInfo.Username, "an admin from the -admins flag")
             }
             USERS[uInfo.Username] = uInfo
             LOG[INFO].Println("Load user", uInfo.Username)
-            file.Close()
         }
     }
 }
 
-// Run command is a basic switch case statement, running required functions based off
-// command codes. A server encoder is created and passed on to the functions so they can respond.
+// Read user decodes the user stored in the file, returns false if it cannot be read
+func readUser(path string) (*UserInfo, bool) {
+    file, err := os.Open(path)
+    if err != nil {
+        LOG[WARNING].Println("Unable to open file", path, ", skipping", err)
+        return nil, false
+    }
+    defer file.Close()
+    decoder := gob.NewDecoder(file)
+    uInfo := NewUserInfo("","")
+    err = decoder.Decode(uInfo)
+    if err != nil {
+        LOG[ERROR].Println(StatusText(StatusDecodeError), err)
+        return nil, false
+    }
+    return uInfo, true
+}
+
+// Migrate user file moves a user stored by an older version to its keyed file, along with any archive
+// The user's own name is used rather than the file name, so files named either way are moved correctly
+// The old file is kept if the user could not be written, and a directory it was moved aside to is removed once empty
+func migrateUserFile(path string, user *UserInfo) {
+    if _, err := os.Stat(UserFile(user.Username)); err == nil {
+        LOG[INFO].Println("Dropping old file", path, "of", user.Username)
+    } else {
+        writeUser(user)
+        if _, err := os.Stat(UserFile(user.Username)); err != nil {
+            LOG[ERROR].Println("Unable to migrate file", path, err)
+            return
+        }
+        os.Rename(EXPORT_DIR + "/" + user.Username + ".zip", ExportPath(user.Username))
+        LOG[INFO].Println("Migrated file", path, "to", UserFile(user.Username))
+    }
+    os.Remove(path)
+    if dir := filepath.Dir(path); dir != DATA_DIR {
+        os.Remove(dir)
+    }
+}
+
+// Build indexes rebuilds the chirp and user search indexes from every loaded user
+func buildIndexes() {
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    INDEX.Clear()
+    USER_INDEX.Clear()
+    for _, user := range USERS {
+        USER_INDEX.Add(user.Username)
+        user.Lock()
+        for _, post := range user.Posts {
+            INDEX.Add(post)
+        }
+        user.Unlock()
+    }
+    LOG[INFO].Println("Indexed", len(USERS), "users and their chirps")
+}
+
+// Load filters builds the filter chain from the configuration file, replicas receive the master's file
+// before their users
+// An invalid file is logged and leaves every chirp and message unfiltered
+func loadFilters() {
+    config, err := ReadFilterConfig()
+    if err == nil {
+        err = FILTERS.Load(config)
+    }
+    if err != nil {
+        LOG[ERROR].Println("Unable to load filters", err)
+        return
+    }
+    LOG[INFO].Println("Loaded filters from", FILTER_CONFIG)
+}
+
+// Run command creates a server encoder on the connection and executes the request, responding
+// through the connection before closing it
 func runCommand(conn net.Conn, request CommandRequest, replica *ReplicaInfo) {
+    executeCommand(gob.NewEncoder(conn), request, replica)
+    conn.Close()
+}
+
+/*
+    Run internal command executes a request issued by the backend itself rather than by the web server.
+    The master propagates it to every replica before running it, exactly like a web server request,
+    so all servers apply the change with the same stamp. The response is discarded.
+*/
+func runInternalCommand(request CommandRequest, replica *ReplicaInfo) {
+    if replica.IsMaster {
+        request = request.Stamp(time.Now())
+        replica.PropagateRequest(request)
+    }
+    executeCommand(gob.NewEncoder(ioutil.Discard), request, replica)
+}
+
+/*
+    Run scheduler checks every second for scheduled posts that are due and publishes them, and for polls
+    past their closing time and closes them. Scheduled posts of suspended accounts wait until the account
+    is reinstated.
+    Only the master does this, through internal commands replicated to every server. Scheduled posts and
+    polls are stored on every replica, so after an election the new master handles whatever is still due.
+    Publishing removes the scheduled post by id on each server, a post published by the old master
+    before failing is no longer scheduled anywhere and is not published again. Closing a poll sends the
+    master's counts, so every server freezes the same results.
+*/
+func runScheduler(replica *ReplicaInfo) {
+    for range time.Tick(time.Second) {
+        if !replica.IsMaster {
+            continue
+        }
+        now := time.Now()
+        var due []CommandRequest
+        USERS_LOCK.RLock()
+        for _, user := range USERS {
+            for _, scheduled := range user.DueScheduled(now) {
+                if user.IsHidden() {
+                    break  // held until the account is reinstated or restored
+                }
+                due = append(due, CommandRequest{CommandPublishScheduled, struct{Username string; Id int; Stamp time.Time}{user.Username, scheduled.Id, now}})
+            }
+            for id, counts := range user.DuePolls(now) {
+                due = append(due, CommandRequest{CommandClosePoll, struct{Username string; Id int; Counts []int}{user.Username, id, counts}})
+            }
+        }
+        USERS_LOCK.RUnlock()
+        for _, request := range due {
+            runInternalCommand(request, replica)
+        }
+    }
+}
+
+/*
+    Run sweeper removes expired posts and posts older than the retention period every SWEEP_INTERVAL.
+    Only the master looks for them, using its own retention flag, and it removes them with an internal
+    command listing the post ids, so every replica removes exactly the same posts.
+*/
+func runSweeper(replica *ReplicaInfo) {
+    for range time.Tick(SWEEP_INTERVAL) {
+        if !replica.IsMaster {
+            continue
+        }
+        now := time.Now()
+        var expired []CommandRequest
+        USERS_LOCK.RLock()
+        for _, user := range USERS {
+            if ids := user.ExpiredPosts(now, *RETENTION); len(ids) > 0 {
+                expired = append(expired, CommandRequest{CommandExpireChirps, struct{Username string; Ids []int}{user.Username, ids}})
+            }
+            if purge := user.PurgeTime(*GRACE); !purge.IsZero() && !purge.After(now) {
+                expired = append(expired, CommandRequest{CommandPurgeAccount, user.Username})
+            }
+        }
+        USERS_LOCK.RUnlock()
+        for _, request := range expired {
+            runInternalCommand(request, replica)
+        }
+    }
+}
+
+// Execute command is a basic switch case statement, running required functions based off
+// command codes. The server encoder is passed on to the functions so they can respond.
+// Functions that depend on the time are given the master's stamp, so replicas reach the same result.
+func executeCommand(serverEncoder *gob.Encoder, request CommandRequest, replica *ReplicaInfo) {
+    request, now := request.Unstamp()
     LOG[INFO].Println("Running command ", request.CommandCode)
-    serverEncoder := gob.NewEncoder(conn)
     switch request.CommandCode {
         case CommandSignup:  // TODO: Map int to function pointer no case switch necessary
-            signup(serverEncoder, request)
+            signup(serverEncoder, request, now)
         case CommandDeleteAccount:
-            deleteAccount(serverEncoder, request)
+            deleteAccount(serverEncoder, request, now)
         case CommandLogin:
             login(serverEncoder, request)
         case CommandFollow:
-            follow(serverEncoder, request)
+            follow(serverEncoder, request, now)
         case CommandUnfollow:
             unfollow(serverEncoder, request)
         case CommandSearch:
             search(serverEncoder, request)
         case CommandChirp:
-            chirp(serverEncoder, request)
+            chirp(serverEncoder, request, now)
         case CommandGetChirps:
             getChrips(serverEncoder, request)
+        case CommandGetNotifications:
+            getNotifications(serverEncoder, request)
+        case CommandReadNotifications:
+            readNotifications(serverEncoder, request)
+        case CommandSendMessage:
+            sendMessage(serverEncoder, request, now)
+        case CommandGetConversations:
+            getConversations(serverEncoder, request)
+        case CommandSetMessagePrivacy:
+            setMessagePrivacy(serverEncoder, request)
+        case CommandDeleteChirp:
+            deleteChirp(serverEncoder, request)
+        case CommandSearchChirps:
+            searchChirps(serverEncoder, request)
+        case CommandSearchUsers:
+            searchUsers(serverEncoder, request)
+        case CommandGetFollowers:
+            getFollowList(serverEncoder, request, true)
+        case CommandGetFollowing:
+            getFollowList(serverEncoder, request, false)
+        case CommandGetProfile:
+            getProfile(serverEncoder, request)
+        case CommandEditProfile:
+            editProfile(serverEncoder, request)
+        case CommandSetPrivacy:
+            setPrivacy(serverEncoder, request)
+        case CommandGetFollowRequests:
+            getFollowRequests(serverEncoder, request)
+        case CommandAnswerFollowRequest:
+            answerFollowRequest(serverEncoder, request, now)
+        case CommandBlock, CommandUnblock, CommandMute, CommandUnmute:
+            blockOrMute(serverEncoder, request)
+        case CommandRecommend:
+            recommend(serverEncoder, request)
+        case CommandGetTrending:
+            getTrending(serverEncoder, request)
+        case CommandScheduleChirp:
+            scheduleChirp(serverEncoder, request, now)
+        case CommandGetScheduled:
+            getScheduled(serverEncoder, request)
+        case CommandPublishScheduled:
+            publishScheduled(serverEncoder, request)
+        case CommandCancelScheduled:
+            cancelScheduled(serverEncoder, request)
+        case CommandSetRetention:
+            setRetention(serverEncoder, request)
+        case CommandExpireChirps:
+            expireChirps(serverEncoder, request)
+        case CommandUploadBlob:
+            uploadBlob(serverEncoder, request)
+        case CommandGetBlob:
+            getBlob(serverEncoder, request)
+        case CommandCreateList:
+            createList(serverEncoder, request)
+        case CommandDeleteList:
+            deleteList(serverEncoder, request)
+        case CommandAddToList, CommandRemoveFromList:
+            editList(serverEncoder, request)
+        case CommandGetLists:
+            getLists(serverEncoder, request)
+        case CommandGetListTimeline:
+            getListTimeline(serverEncoder, request)
+        case CommandBookmark, CommandRemoveBookmark:
+            bookmark(serverEncoder, request)
+        case CommandGetBookmarks:
+            getBookmarks(serverEncoder, request)
+        case CommandPin:
+            pin(serverEncoder, request)
+        case CommandPollChirp:
+            pollChirp(serverEncoder, request, now)
+        case CommandVote:
+            vote(serverEncoder, request, now)
+        case CommandClosePoll:
+            closePoll(serverEncoder, request)
+        case CommandReport:
+            report(serverEncoder, request, now)
+        case CommandGetModerationQueue:
+            getModerationQueue(serverEncoder, request)
+        case CommandModerate:
+            moderate(serverEncoder, request, now)
+        case CommandAppeal:
+            appeal(serverEncoder, request)
+        case CommandGetStanding:
+            getStanding(serverEncoder, request)
+        case CommandGetFilters:
+            getFilters(serverEncoder, request)
+        case CommandSetFilters:
+            setFilters(serverEncoder, request)
+        case CommandGetAccounts:
+            getAccounts(serverEncoder, request)
+        case CommandSetRole:
+            setRole(serverEncoder, request, now)
+        case CommandExport:
+            exportData(serverEncoder, request, now)
+        case CommandGetExport:
+            getExport(serverEncoder, request)
+        case CommandDownloadExport:
+            downloadExport(serverEncoder, request)
+        case CommandPurgeAccount:
+            purgeAccount(serverEncoder, request)
+        case CommandRestoreAccount:
+            restoreAccount(serverEncoder, request)
+        case CommandRename:
+            renameAccount(serverEncoder, request, now)
         case CommandSendPing:
             LOG[INFO].Println("Ping Received from Master")
             id, ok := request.Data.(int)
@@ -235,7 +586,6 @@ func runCommand(conn net.Conn, request CommandRequest, replica *ReplicaInfo) {
         default:
             LOG[WARNING].Println("Invalid command ", request.CommandCode, ", ignoring.")
     }
-    conn.Close()
 }
 
 /*
@@ -246,8 +596,9 @@ func runCommand(conn net.Conn, request CommandRequest, replica *ReplicaInfo) {
     Signup then tries to create a file ../../data/*username* and encode a new UserInfo
     object into the file
     If this is successful, the new UserInfo object is added to the USERS map
+    Name reservations and the join date use the master's stamp, so every replica decides the same way
 */
-func signup(serverEncoder *gob.Encoder, request CommandRequest) {
+func signup(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
     userAndPass, ok := request.Data.(struct{Username, Password string})
     if !ok {
         LOG[ERROR].Println(StatusText(StatusDecodeError))
@@ -255,29 +606,116 @@ func signup(serverEncoder *gob.Encoder, request CommandRequest) {
         return
     }
 
-    if _, err := os.Stat("../../data/" + userAndPass.Username); !os.IsNotExist(err) {
-       LOG[INFO].Println("Username", userAndPass.Username, "already exists", err)
+    if status := ValidateUsername(userAndPass.Username); status != StatusAccepted {
+        LOG[INFO].Println(StatusText(status), userAndPass.Username)
+        serverEncoder.Encode(CommandResponse{false, status, nil})
+        return
+    }
+    USERS_LOCK.RLock()
+    taken := usernameTaken(userAndPass.Username, nil)
+    reserved := nameReserved(userAndPass.Username, nil, now)
+    USERS_LOCK.RUnlock()
+    if taken {
+       LOG[INFO].Println("Username", userAndPass.Username, "already exists")
        serverEncoder.Encode(CommandResponse{false, StatusDuplicateUser, nil})
        return
     }
+    if reserved {
+        LOG[INFO].Println(StatusText(StatusNameReserved), userAndPass.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusNameReserved, nil})
+        return
+    }
 
 
     newUser :=  NewUserInfo(userAndPass.Username, userAndPass.Password)
+    newUser.Joined = now
+    if isBootstrapAdmin(newUser.Username) {
+        newUser.Role = RoleAdmin
+    }
     writeUser(newUser)
 
     USERS_LOCK.Lock()
     USERS[newUser.Username] = newUser
     USERS_LOCK.Unlock()
+    USER_INDEX.Add(newUser.Username)
 
     LOG[INFO].Println("Created user", newUser.Username)
     serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
 }
 
-// Delete account takes a username, then finds the corresponding user
-// It then calls unfollow on the current user and has the current user unfollow
-// all users it is currently following to remove dead references
+/*
+    Delete account takes a username and password and marks the account deleted. The account is hidden
+    from everyone right away but keeps its data, follows and username, so logging in before the grace
+    period ends can restore it.
+    Once the grace period has passed the master's sweeper purges the account on every server.
+*/
+func deleteAccount(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    userAndPass, ok := request.Data.(struct{Username, Password string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[userAndPass.Username]
+    if !ok || user.IsDeleted() {
+        LOG[INFO].Println(StatusText(StatusUserNotFound), userAndPass.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if user.Password != userAndPass.Password {
+        LOG[INFO].Println(StatusText(StatusIncorrectPassword), userAndPass.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusIncorrectPassword, nil})
+        return
+    }
+    user.MarkDeleted(now)
+    writeUser(user)
+    LOG[INFO].Println("Deleted user", user.Username, "pending purge")
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Restore account takes a username and password and restores the deleted account before it is purged
+func restoreAccount(serverEncoder *gob.Encoder, request CommandRequest) {
+    userAndPass, ok := request.Data.(struct{Username, Password string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[userAndPass.Username]
+    if !ok {
+        LOG[INFO].Println(StatusText(StatusUserNotFound), userAndPass.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if user.Password != userAndPass.Password {
+        LOG[INFO].Println(StatusText(StatusIncorrectPassword), userAndPass.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusIncorrectPassword, nil})
+        return
+    }
+    if !user.RestoreAccount() {
+        LOG[INFO].Println("User", user.Username, "is not deleted")
+        serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+        return
+    }
+    writeUser(user)
+    LOG[INFO].Println("Restored user", user.Username)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Purge account takes the username of a deleted account, sent by the master's sweeper, then finds the
+// corresponding user
+// It then removes every reference other users hold to the account with RemoveReferences
 // It then removes the user from the map and deletes the user from
-func deleteAccount(serverEncoder *gob.Encoder, request CommandRequest) {
+// An account restored in the meantime is left alone
+func purgeAccount(serverEncoder *gob.Encoder, request CommandRequest) {
     username, ok := request.Data.(string)
     if !ok {
         LOG[ERROR].Println(StatusText(StatusDecodeError))
@@ -288,21 +726,122 @@ func deleteAccount(serverEncoder *gob.Encoder, request CommandRequest) {
     USERS_LOCK.Lock()
     defer USERS_LOCK.Unlock()
     user, ok := USERS[username]
-    if !ok {
+    if !ok || !user.IsDeleted() {
         LOG[INFO].Println(StatusText(StatusUserNotFound), username)
         serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
         return
     }
-    for _, otherUser := range user.FollowedBy {
-        USERS[otherUser].UnFollow(user)
-        writeUser(USERS[otherUser])
+    for _, post := range user.Posts {
+        INDEX.Remove(PostRef{post.Poster, post.Id})
     }
-    for key := range user.Following {
-        user.UnFollow(USERS[key])
-        writeUser(USERS[key])
+    for _, otherUser := range USERS {
+        if otherUser != user && otherUser.RemoveReferences(user.Username) {
+            writeUser(otherUser)
+        }
     }
-    os.Remove("../../data/" + user.Username)
+    os.Remove(UserFile(user.Username))
+    RemoveArchive(user.Username)
+    EXPORTS_LOCK.Lock()
+    delete(EXPORTS, user.Username)
+    EXPORTS_LOCK.Unlock()
     delete(USERS, user.Username)
+    USER_INDEX.Remove(user.Username)
+    LOG[INFO].Println("Purged user", user.Username)
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Username taken checks if a user other than the given one has the name, ignoring case, or a file for it
+// The caller must hold USERS_LOCK
+func usernameTaken(name string, except *UserInfo) bool {
+    for other, user := range USERS {
+        if user != except && strings.EqualFold(other, name) {
+            return true
+        }
+    }
+    _, err := os.Stat(UserFile(name))
+    return !os.IsNotExist(err)
+}
+
+// Name reserved checks if a user other than the given one renamed away from the name recently
+// The caller must hold USERS_LOCK
+func nameReserved(name string, except *UserInfo, now time.Time) bool {
+    for _, user := range USERS {
+        if user != except && user.Reserves(name, now) {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+    Rename account takes a username and a new username and renames the account. The data file, every
+    reference other users hold to the account, the poster of its chirps and the search indexes are
+    updated while the user map is locked, so no command sees the account half renamed.
+    The old username stays reserved for the account for the -name-reserve period, so nobody else can
+    sign up or rename to it and impersonate the user. The webserver moves the login cookie to the new name.
+    Reservations are checked and set with the master's stamp, so every replica keeps them until the same time.
+*/
+func renameAccount(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    renameInfo, ok := request.Data.(struct{Username, NewName string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    name := renameInfo.NewName
+    if status := ValidateUsername(name); status != StatusAccepted || name == renameInfo.Username {
+        LOG[INFO].Println(StatusText(StatusInvalidUsername), name)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidUsername, nil})
+        return
+    }
+
+    USERS_LOCK.Lock()
+    defer USERS_LOCK.Unlock()
+    user, ok := USERS[renameInfo.Username]
+    if !ok || user.IsDeleted() {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), renameInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if usernameTaken(name, user) {
+        LOG[INFO].Println("Username", name, "already exists")
+        serverEncoder.Encode(CommandResponse{false, StatusDuplicateUser, nil})
+        return
+    }
+    if nameReserved(name, user, now) {
+        LOG[INFO].Println(StatusText(StatusNameReserved), name)
+        serverEncoder.Encode(CommandResponse{false, StatusNameReserved, nil})
+        return
+    }
+
+    old := user.Username
+    for _, post := range user.Posts {
+        INDEX.Remove(PostRef{old, post.Id})
+    }
+    user.Rename(name, now.Add(*RESERVE), now)
+    for _, other := range USERS {
+        if other.RenameReferences(old, name) && other != user {
+            writeUser(other)
+        }
+    }
+    delete(USERS, old)
+    USERS[name] = user
+    writeUser(user)
+    os.Remove(UserFile(old))
+    USER_INDEX.Remove(old)
+    USER_INDEX.Add(name)
+    for _, post := range user.Posts {
+        INDEX.Add(post)
+    }
+    RenameArchive(old, name)
+    EXPORTS_LOCK.Lock()
+    if status, ok := EXPORTS[old]; ok {
+        delete(EXPORTS, old)
+        EXPORTS[name] = status
+    }
+    EXPORTS_LOCK.Unlock()
+    LOG[INFO].Println("Renamed user", old, "to", name)
+
     serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
 }
 
@@ -330,6 +869,11 @@ func login(serverEncoder *gob.Encoder, request CommandRequest) {
         serverEncoder.Encode(CommandResponse{false, StatusIncorrectPassword, nil})
         return
     }
+    if purge := user.PurgeTime(*GRACE); !purge.IsZero() {
+        LOG[INFO].Println(StatusText(StatusAccountDeleted), user.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusAccountDeleted, purge.Format(time.RFC1123)[0:len(time.RFC1123)-4]})
+        return
+    }
 
     LOG[INFO].Println("User", user.Username, "login")
     serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
@@ -337,8 +881,9 @@ func login(serverEncoder *gob.Encoder, request CommandRequest) {
 
 
 // Follow takes two strings from the command response and then calls follow on the first to the second
-// It returns relevant error information if the follow fails or one of the users does not exist
-func follow(serverEncoder *gob.Encoder, request CommandRequest) {
+// If the second user is private a follow request is sent instead and StatusFollowRequested is returned
+// It returns relevant error information if the follow fails, either user blocked the other or one of the users does not exist
+func follow(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
     users, ok := request.Data.(struct{Username1, Username2 string})
     if !ok {
         LOG[ERROR].Println(StatusText(StatusDecodeError))
@@ -350,16 +895,33 @@ func follow(serverEncoder *gob.Encoder, request CommandRequest) {
     defer USERS_LOCK.RUnlock()
     user, ok := USERS[users.Username1]
     user2, ok2 := USERS[users.Username2]
-    if !ok || !ok2 {
+    if !ok || !ok2 || user2.IsDeleted() {
         LOG[WARNING].Println(StatusText(StatusUserNotFound))
         serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
         return
     }
+    if EitherBlocked(user, user2) {
+        LOG[INFO].Println("User", user.Username, "and", user2.Username, "are blocked")
+        serverEncoder.Encode(CommandResponse{false, StatusBlocked, nil})
+        return
+    }
+    if user2.IsPrivate() {
+        if !user.RequestFollow(user2) {
+            LOG[ERROR].Println("User", user.Username, "unable to request to follow", user2.Username)
+            serverEncoder.Encode(CommandResponse{false, StatusInternalError, nil})
+            return
+        }
+        user2.Notify(NotifyFollowRequest, user.Username, "", now)
+        writeUser(user2)
+        serverEncoder.Encode(CommandResponse{true, StatusFollowRequested, nil})
+        return
+    }
     if !user.Follow(user2) {
         LOG[ERROR].Println("User", user.Username, "unable to follow", user2.Username)
         serverEncoder.Encode(CommandResponse{false, StatusInternalError, nil})
         return
     }
+    user2.Notify(NotifyFollow, user.Username, "", now)
     writeUser(user)
     writeUser(user2)
 
@@ -368,6 +930,7 @@ func follow(serverEncoder *gob.Encoder, request CommandRequest) {
 
 // Unfollow is similar to above but with reverse functionality, kept as separate functions
 // for ease of front end data sending
+// A pending follow request is withdrawn if the first user does not follow the second yet
 func unfollow(serverEncoder *gob.Encoder, request CommandRequest) {
     users, ok := request.Data.(struct{Username1, Username2 string})
     if !ok {
@@ -385,6 +948,11 @@ func unfollow(serverEncoder *gob.Encoder, request CommandRequest) {
         serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
         return
     }
+    if user.CancelFollowRequest(user2) {
+        writeUser(user2)
+        serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+        return
+    }
     if !user.UnFollow(user2) {
         LOG[ERROR].Println("User", user.Username, "unable to unfollow", user2.Username)
         serverEncoder.Encode(CommandResponse{false, StatusInternalError, nil})
@@ -398,7 +966,7 @@ func unfollow(serverEncoder *gob.Encoder, request CommandRequest) {
 
 
 // Search takes a command request with two strings: the searcher username and the target username
-// It then performs the specified search and returns if the user is following the target
+// It then performs the specified search and returns if the user is following the target or waiting for approval
 // It returns relivant error info if one of the users does not exist
 func search(serverEncoder *gob.Encoder, request CommandRequest) {
     username, ok := request.Data.(struct{Searcher, Target string})
@@ -418,6 +986,8 @@ func search(serverEncoder *gob.Encoder, request CommandRequest) {
         LOG[INFO].Println("User", user1.Username, "search", user2.Username)
         if user1.IsFollowing(user2) {
             serverEncoder.Encode(CommandResponse{true, StatusUserFollowed, "Unfollow"})
+        } else if user1.HasRequested(user2) {
+            serverEncoder.Encode(CommandResponse{true, StatusFollowRequested, "Requested"})
         } else {
             serverEncoder.Encode(CommandResponse{true, StatusUserNotFollowed, "Follow"})
         }
@@ -425,11 +995,11 @@ func search(serverEncoder *gob.Encoder, request CommandRequest) {
     USERS_LOCK.RUnlock()
 }
 
-// Chirp takes a command request with a Username Post string combo and calls the corresponding
-// write Post function for the specified user
-// It writes the change to a file and then responds with CommandResponse containing corresponding error info
-func chirp(serverEncoder *gob.Encoder, request CommandRequest) {
-    postInfo, ok := request.Data.(struct{Username, Post string})
+// Search users takes the searcher's username and the search text and responds with the usernames
+// matching by prefix, substring or edit distance, each with a follower count and whether the searcher follows them
+// The searcher and suspended accounts are left out of the results
+func searchUsers(serverEncoder *gob.Encoder, request CommandRequest) {
+    searchInfo, ok := request.Data.(struct{Searcher, Query string})
     if !ok {
         LOG[ERROR].Println(StatusText(StatusDecodeError))
         serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
@@ -438,23 +1008,138 @@ func chirp(serverEncoder *gob.Encoder, request CommandRequest) {
 
     USERS_LOCK.RLock()
     defer USERS_LOCK.RUnlock()
-    user, ok := USERS[postInfo.Username]
+    searcher, ok := USERS[searchInfo.Searcher]
     if !ok {
-        LOG[WARNING].Println(StatusText(StatusUserNotFound), postInfo.Username)
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), searchInfo.Searcher)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    LOG[INFO].Println("User", searcher.Username, "search users", searchInfo.Query)
+    result := []UserResult{}
+    for _, name := range USER_INDEX.Search(searchInfo.Query) {
+        user, ok := USERS[name]
+        if !ok || user == searcher || user.IsHidden() {
+            continue
+        }
+        result = append(result, userResult(searcher, user))
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, result})
+}
+
+// Get follow list takes a viewer, a target and a page number and responds with that page of the
+// target's followers, or of the users the target follows when followers is false
+// Each listed user comes with their counts and whether the viewer follows them
+func getFollowList(serverEncoder *gob.Encoder, request CommandRequest, followers bool) {
+    listInfo, ok := request.Data.(struct{Viewer, Target string; Page int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    viewer, ok := USERS[listInfo.Viewer]
+    target, ok2 := USERS[listInfo.Target]
+    if !ok || !ok2 || (target != viewer && target.IsDeleted()) {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound))
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+
+    names := target.GetFollowing()
+    if followers {
+        names = target.GetFollowers()
+    }
+    if listInfo.Page < 1 {
+        listInfo.Page = 1
+    }
+    start := (listInfo.Page - 1) * PAGE_SIZE
+    if start > len(names) {
+        start = len(names)
+    }
+    end := start + PAGE_SIZE
+    if end > len(names) {
+        end = len(names)
+    }
+
+    page := UserPage{Owner: userResult(viewer, target), Users: []UserResult{}, Page: listInfo.Page, HasMore: end < len(names)}
+    for _, name := range names[start:end] {
+        if user, ok := USERS[name]; ok && !user.IsDeleted() {
+            page.Users = append(page.Users, userResult(viewer, user))
+        }
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, page})
+}
+
+// Get profile takes a viewer, a target and a page number and responds with the target's profile
+// holding their display name, bio, join date, counts and that page of their own posts
+// The posts of a private account are only included for its followers and never between blocked users,
+// posts whose visibility hides them from the viewer are left out
+func getProfile(serverEncoder *gob.Encoder, request CommandRequest) {
+    profileInfo, ok := request.Data.(struct{Viewer, Target string; Page int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    viewer, ok := USERS[profileInfo.Viewer]
+    target, ok2 := USERS[profileInfo.Target]
+    if !ok || !ok2 {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound))
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if target != viewer && target.IsDeleted() {
+        LOG[INFO].Println(StatusText(StatusUserNotFound), profileInfo.Target)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    hidden := !target.VisibleTo(viewer) || viewer.HasBlocked(target.Username)
+    profile := Profile{User: userResult(viewer, target), Hidden: hidden, Suspended: target.IsSuspended()}
+    target.FillProfile(&profile, viewer, profileInfo.Page)
+    if viewer == target {
+        profile.Retention = target.GetRetention()
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, profile})
+}
+
+// Edit profile takes a username, display name and bio and stores them on the user
+// It fails with StatusInvalidProfile if either is too long, the change is written to the user's file
+func editProfile(serverEncoder *gob.Encoder, request CommandRequest) {
+    profileInfo, ok := request.Data.(struct{Username, DisplayName, Bio string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    if utf8.RuneCountInString(profileInfo.DisplayName) > MAX_DISPLAY_NAME_LENGTH || utf8.RuneCountInString(profileInfo.Bio) > MAX_BIO_LENGTH {
+        LOG[INFO].Println(StatusText(StatusInvalidProfile), profileInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidProfile, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[profileInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), profileInfo.Username)
         serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
         return
     }
-    user.WritePost(postInfo.Post)
+    user.EditProfile(profileInfo.DisplayName, profileInfo.Bio)
     writeUser(user)
 
     serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
 }
 
-// Get chirps takes in a CommandRequest which contains a string that represents the user that
-// the frontend is trying to get the chirps of
-// The corresponding call to getChirps is called and are encoded back to the front end
-func getChrips(serverEncoder *gob.Encoder, request CommandRequest) {
-    username, ok := request.Data.(string)
+// Set privacy takes a username and whether the account should be private
+// Making an account public approves all of its pending follow requests, changes are written to the files
+func setPrivacy(serverEncoder *gob.Encoder, request CommandRequest) {
+    setting, ok := request.Data.(struct{Username string; Private bool})
     if !ok {
         LOG[ERROR].Println(StatusText(StatusDecodeError))
         serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
@@ -463,13 +1148,1622 @@ func getChrips(serverEncoder *gob.Encoder, request CommandRequest) {
 
     USERS_LOCK.RLock()
     defer USERS_LOCK.RUnlock()
-    user, ok := USERS[username]
+    user, ok := USERS[setting.Username]
     if !ok {
-        LOG[WARNING].Println(StatusText(StatusUserNotFound), username)
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), setting.Username)
         serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
         return
     }
-    serverEncoder.Encode(CommandResponse{true, StatusAccepted, user.GetAllChirps(USERS)})
+    user.SetPrivate(setting.Private)
+    if !setting.Private {
+        for _, name := range user.GetFollowRequests() {
+            requester, ok := USERS[name]
+            if ok && user.AnswerFollowRequest(requester, true) {
+                writeUser(requester)
+            }
+        }
+    }
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Set retention takes a username and a duration, the user's posts older than it are removed by the sweeper
+// A zero duration keeps posts forever, negative durations fail with StatusInvalidRetention
+func setRetention(serverEncoder *gob.Encoder, request CommandRequest) {
+    setting, ok := request.Data.(struct{Username string; Retention time.Duration})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    if setting.Retention < 0 {
+        LOG[INFO].Println(StatusText(StatusInvalidRetention), setting.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidRetention, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[setting.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), setting.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    user.SetRetention(setting.Retention)
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Get follow requests takes a username and responds with the users waiting for approval to follow them
+func getFollowRequests(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, user.GetFollowRequests()})
+}
+
+// Answer follow request takes a username, the requester and whether the request is approved
+// Approving makes the requester a follower and notifies them, changes are written to both files
+func answerFollowRequest(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    answer, ok := request.Data.(struct{Username, Requester string; Approve bool})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[answer.Username]
+    requester, ok2 := USERS[answer.Requester]
+    if !ok || !ok2 {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound))
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if !user.AnswerFollowRequest(requester, answer.Approve) {
+        LOG[WARNING].Println("No follow request from", requester.Username, "to", user.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFollowed, nil})
+        return
+    }
+    if answer.Approve {
+        requester.Notify(NotifyFollowAccepted, user.Username, "", now)
+    }
+    writeUser(user)
+    writeUser(requester)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Block or mute takes two usernames and has the first block, unblock, mute or unmute the second
+// depending on the command code
+// Blocking removes the follow relationship in both directions, changes are written to the files
+func blockOrMute(serverEncoder *gob.Encoder, request CommandRequest) {
+    users, ok := request.Data.(struct{Username1, Username2 string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[users.Username1]
+    user2, ok2 := USERS[users.Username2]
+    if !ok || !ok2 || user == user2 {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound))
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+
+    var changed bool
+    switch request.CommandCode {
+        case CommandBlock:
+            changed = user.Block(user2)
+        case CommandUnblock:
+            changed = user.Unblock(user2.Username)
+        case CommandMute:
+            changed = user.Mute(user2.Username)
+        case CommandUnmute:
+            changed = user.Unmute(user2.Username)
+    }
+    if !changed {
+        LOG[WARNING].Println("User", user.Username, "command", request.CommandCode, "had no effect on", user2.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusInternalError, nil})
+        return
+    }
+    writeUser(user)
+    if request.CommandCode == CommandBlock {
+        writeUser(user2)
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Create list takes a username, list name and privacy setting and creates an empty list for the user
+// It fails with StatusInvalidList if the name is empty, too long or already used, or the user has too many lists
+func createList(serverEncoder *gob.Encoder, request CommandRequest) {
+    listInfo, ok := request.Data.(struct{Username, Name string; Private bool})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[listInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), listInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if !ValidListName(listInfo.Name) || !user.CreateList(listInfo.Name, listInfo.Private) {
+        LOG[INFO].Println(StatusText(StatusInvalidList), listInfo.Username, listInfo.Name)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidList, nil})
+        return
+    }
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Delete list takes a username and list name and deletes the user's list
+func deleteList(serverEncoder *gob.Encoder, request CommandRequest) {
+    listInfo, ok := request.Data.(struct{Username, Name string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[listInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), listInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if !user.DeleteList(listInfo.Name) {
+        LOG[WARNING].Println(StatusText(StatusListNotFound), listInfo.Username, listInfo.Name)
+        serverEncoder.Encode(CommandResponse{false, StatusListNotFound, nil})
+        return
+    }
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Edit list takes a username, list name and member and adds the member to or removes them from the user's list
+// depending on the command code
+// Users that blocked the list owner or were blocked by them cannot be added, the list is written to the user's file
+func editList(serverEncoder *gob.Encoder, request CommandRequest) {
+    listInfo, ok := request.Data.(struct{Username, Name, Member string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[listInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), listInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if _, ok := user.GetList(listInfo.Name, user.Username); !ok {
+        LOG[WARNING].Println(StatusText(StatusListNotFound), listInfo.Username, listInfo.Name)
+        serverEncoder.Encode(CommandResponse{false, StatusListNotFound, nil})
+        return
+    }
+
+    if request.CommandCode == CommandAddToList {
+        member, ok := USERS[listInfo.Member]
+        if !ok {
+            LOG[WARNING].Println(StatusText(StatusUserNotFound), listInfo.Member)
+            serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+            return
+        }
+        if member != user && EitherBlocked(user, member) {
+            LOG[INFO].Println(StatusText(StatusBlocked), listInfo.Username, listInfo.Member)
+            serverEncoder.Encode(CommandResponse{false, StatusBlocked, nil})
+            return
+        }
+        if !user.AddToList(listInfo.Name, listInfo.Member) {
+            LOG[INFO].Println(StatusText(StatusInvalidList), listInfo.Username, listInfo.Name)
+            serverEncoder.Encode(CommandResponse{false, StatusInvalidList, nil})
+            return
+        }
+    } else {
+        user.RemoveFromList(listInfo.Name, listInfo.Member)
+    }
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Get lists takes a viewer and owner and responds with the owner's lists the viewer can see, sorted by name
+// Private lists are only included for the owner
+func getLists(serverEncoder *gob.Encoder, request CommandRequest) {
+    listInfo, ok := request.Data.(struct{Viewer, Owner string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    owner, ok := USERS[listInfo.Owner]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), listInfo.Owner)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, owner.GetLists(listInfo.Viewer)})
+}
+
+// Get list timeline takes a viewer, owner and list name and responds with the list and the posts of
+// its members the viewer can see, newest first
+func getListTimeline(serverEncoder *gob.Encoder, request CommandRequest) {
+    listInfo, ok := request.Data.(struct{Viewer, Owner, Name string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    viewer, ok := USERS[listInfo.Viewer]
+    owner, ok2 := USERS[listInfo.Owner]
+    if !ok || !ok2 {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound))
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    timeline, ok := owner.ListChirps(listInfo.Name, viewer, USERS)
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusListNotFound), listInfo.Owner, listInfo.Name)
+        serverEncoder.Encode(CommandResponse{false, StatusListNotFound, nil})
+        return
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, timeline})
+}
+
+// Recommend takes a username and responds with accounts the user may want to follow, best first,
+// each with the reason it was suggested
+func recommend(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    recommendations := user.Recommend(USERS, time.Now())
+    for i := range recommendations {
+        recommendations[i].User = userResult(user, USERS[recommendations[i].User.Username])
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, recommendations})
+}
+
+// Get trending takes the name of a window (hour, day or week) and responds with the hashtags and
+// terms trending over that window, unknown windows fall back to a day
+func getTrending(serverEncoder *gob.Encoder, request CommandRequest) {
+    window, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, ComputeTrends(USERS, window, time.Now())})
+}
+
+// User result builds the summary of a user shown in user lists as seen by the viewer
+func userResult(viewer, user *UserInfo) UserResult {
+    following := viewer != user && viewer.IsFollowing(user)
+    requested := viewer != user && viewer.HasRequested(user)
+    return UserResult{
+        user.Username,
+        user.FollowerCount(),
+        user.FollowingCount(),
+        following,
+        requested,
+        user.IsPrivate(),
+        viewer.HasBlocked(user.Username),
+        viewer.HasMuted(user.Username),
+    }
+}
+
+// Chirp takes a command request with a Username Post string combo and calls the corresponding
+// write Post function for the specified user
+// The message is normalized and rejected with StatusEmptyPost or StatusPostTooLong by ValidatePost
+// It writes the change to a file along with the files of any mentioned users and then responds
+// with CommandResponse containing corresponding error info
+func chirp(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    postInfo, ok := request.Data.(struct{Username, Post string; Lifetime time.Duration; Attachments []Attachment; Visibility int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    message, status := ValidatePost(postInfo.Post)
+    if status != StatusAccepted {
+        LOG[INFO].Println(StatusText(status), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, status, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[postInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if user.IsSuspended() {
+        LOG[INFO].Println(StatusText(StatusSuspended), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusSuspended, nil})
+        return
+    }
+    if !ValidVisibility(postInfo.Visibility) {
+        LOG[WARNING].Println(StatusText(StatusInvalidVisibility), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidVisibility, nil})
+        return
+    }
+    if len(postInfo.Attachments) > MAX_ATTACHMENTS {
+        LOG[WARNING].Println(StatusText(StatusInvalidAttachment), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidAttachment, nil})
+        return
+    }
+    for _, attachment := range postInfo.Attachments {
+        if !BlobExists(attachment.Hash) {
+            LOG[WARNING].Println(StatusText(StatusBlobNotFound), attachment.Hash)
+            serverEncoder.Encode(CommandResponse{false, StatusBlobNotFound, nil})
+            return
+        }
+    }
+    result := FILTERS.Run(Draft{user, message, false, now})
+    if result.Verdict == FilterReject {
+        LOG[INFO].Println(StatusText(StatusFiltered), postInfo.Username, result.Reason)
+        serverEncoder.Encode(CommandResponse{false, StatusFiltered, nil})
+        return
+    }
+    draft := Post{Message: message, Stamp: now, Attachments: postInfo.Attachments, Visibility: postInfo.Visibility}
+    result.Apply(&draft)
+    if postInfo.Lifetime > 0 {
+        draft.ExpiresAt = draft.Stamp.Add(postInfo.Lifetime)
+    }
+    post, mentioned := user.WritePost(draft, USERS)
+    INDEX.Add(post)
+    writeUser(user)
+    for _, name := range mentioned {
+        writeUser(USERS[name])
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Poll chirp takes a username, post, poll options and poll duration and adds a chirp with the poll
+// The question is validated like any other post and the options are normalized
+// It fails with StatusInvalidPoll if the options or duration are outside the limits
+func pollChirp(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    postInfo, ok := request.Data.(struct{Username, Post string; Options []string; Duration time.Duration})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    message, status := ValidatePost(postInfo.Post)
+    if status != StatusAccepted {
+        LOG[INFO].Println(StatusText(status), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, status, nil})
+        return
+    }
+    for i := range postInfo.Options {
+        postInfo.Options[i] = NormalizeText(postInfo.Options[i])
+    }
+    poll, ok := NewPoll(postInfo.Options, postInfo.Duration, now)
+    if !ok {
+        LOG[INFO].Println(StatusText(StatusInvalidPoll), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidPoll, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[postInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if user.IsSuspended() {
+        LOG[INFO].Println(StatusText(StatusSuspended), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusSuspended, nil})
+        return
+    }
+    result := FILTERS.Run(Draft{user, message, false, now})
+    if result.Verdict == FilterReject {
+        LOG[INFO].Println(StatusText(StatusFiltered), postInfo.Username, result.Reason)
+        serverEncoder.Encode(CommandResponse{false, StatusFiltered, nil})
+        return
+    }
+    draft := Post{Message: message, Stamp: now, Poll: poll}
+    result.Apply(&draft)
+    post, mentioned := user.WritePost(draft, USERS)
+    INDEX.Add(post)
+    writeUser(user)
+    for _, name := range mentioned {
+        writeUser(USERS[name])
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Vote takes a username, the poster and id of a post with a poll and the index of an option and counts the vote
+// Each user votes once per poll and only in open polls on posts they can see
+func vote(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    voteInfo, ok := request.Data.(struct{Username, Poster string; Id, Option int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[voteInfo.Username]
+    poster, ok2 := USERS[voteInfo.Poster]
+    if !ok || !ok2 {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound))
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if post, ok := poster.GetPost(voteInfo.Id); !ok || !poster.CanSeePost(user, post) {
+        LOG[WARNING].Println(StatusText(StatusPostNotFound), voteInfo.Poster, voteInfo.Id)
+        serverEncoder.Encode(CommandResponse{false, StatusPostNotFound, nil})
+        return
+    }
+    status := poster.Vote(voteInfo.Id, voteInfo.Username, voteInfo.Option, now)
+    if status != StatusAccepted {
+        LOG[INFO].Println(StatusText(status), voteInfo.Username, voteInfo.Poster, voteInfo.Id)
+        serverEncoder.Encode(CommandResponse{false, status, nil})
+        return
+    }
+    writeUser(poster)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Close poll takes a username, post id and the final counts chosen by the master's scheduler and
+// freezes the poll with those counts, polls already closed are left unchanged
+func closePoll(serverEncoder *gob.Encoder, request CommandRequest) {
+    pollInfo, ok := request.Data.(struct{Username string; Id int; Counts []int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[pollInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), pollInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if !user.ClosePoll(pollInfo.Id, pollInfo.Counts) {
+        LOG[WARNING].Println(StatusText(StatusPollClosed), pollInfo.Username, pollInfo.Id)
+        serverEncoder.Encode(CommandResponse{false, StatusPollClosed, nil})
+        return
+    }
+    writeUser(user)
+    LOG[INFO].Println("Closed poll", pollInfo.Id, "of", pollInfo.Username, pollInfo.Counts)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Is bootstrap admin checks if the user is listed in the -admins flag, listed users are made admins so
+// a new cluster has someone to assign the other roles
+func isBootstrapAdmin(username string) bool {
+    for _, name := range strings.Split(*ADMINS, ",") {
+        if strings.TrimSpace(name) == username && username != "" {
+            return true
+        }
+    }
+    return false
+}
+
+// Has role checks if the user exists, is not suspended or deleted and has at least the given role
+// The caller must hold USERS_LOCK
+func hasRole(username string, role int) bool {
+    user, ok := USERS[username]
+    return ok && user.HasRole(role) && !user.IsHidden()
+}
+
+// Report takes a reporter, the reported user, a post id, 0 to report the account itself, and a reason
+// and files a report for admins to review
+// The reported post must be visible to the reporter and users cannot report themselves
+func report(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    reportInfo, ok := request.Data.(struct{Reporter, Target string; Id int; Reason string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    reason := NormalizeText(reportInfo.Reason)
+    if !ValidReason(reason) || reportInfo.Reporter == reportInfo.Target {
+        LOG[INFO].Println(StatusText(StatusInvalidReport), reportInfo.Reporter, reportInfo.Target)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidReport, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    reporter, ok := USERS[reportInfo.Reporter]
+    target, ok2 := USERS[reportInfo.Target]
+    if !ok || !ok2 {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound))
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if reportInfo.Id != 0 {
+        if post, ok := target.GetPost(reportInfo.Id); !ok || !target.CanSeePost(reporter, post) {
+            LOG[WARNING].Println(StatusText(StatusPostNotFound), reportInfo.Target, reportInfo.Id)
+            serverEncoder.Encode(CommandResponse{false, StatusPostNotFound, nil})
+            return
+        }
+    }
+    if target.AddReport(reporter.Username, reportInfo.Id, reason, now) {
+        LOG[INFO].Println("User", reportInfo.Reporter, "reported", reportInfo.Target, reportInfo.Id)
+        writeUser(target)
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Get moderation queue takes a moderator's username and responds with every reported or appealed post
+// and account, fails with StatusForbidden for users below the moderator role
+func getModerationQueue(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    if !hasRole(username, RoleModerator) {
+        LOG[WARNING].Println(StatusText(StatusForbidden), username)
+        serverEncoder.Encode(CommandResponse{false, StatusForbidden, nil})
+        return
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, ModerationQueue(USERS)})
+}
+
+/*
+    Moderate takes a moderator's username, the target user, a post id, 0 for the account itself, an action and
+    a reason, and applies the action: dismissing the reports, taking down, restoring or approving a held post,
+    suspending or reinstating the account, or denying a pending appeal.
+    Takedowns and suspensions need a reason, which is sent to the affected user in a notification.
+    Fails with StatusForbidden if the user is below the moderator role, or suspends or reinstates an account
+    without outranking it, and StatusInvalidModeration if the action does not apply, such as reinstating
+    an account that is not suspended.
+*/
+func moderate(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    actionInfo, ok := request.Data.(struct{Admin, Target string; Id, Action int; Reason string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    reason := NormalizeText(actionInfo.Reason)
+    if (actionInfo.Action == ModerateTakedown || actionInfo.Action == ModerateSuspend) && !ValidReason(reason) {
+        LOG[INFO].Println(StatusText(StatusInvalidReport), actionInfo.Admin)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidReport, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    if !hasRole(actionInfo.Admin, RoleModerator) {
+        LOG[WARNING].Println(StatusText(StatusForbidden), actionInfo.Admin)
+        serverEncoder.Encode(CommandResponse{false, StatusForbidden, nil})
+        return
+    }
+    target, ok := USERS[actionInfo.Target]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), actionInfo.Target)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    account := actionInfo.Action == ModerateSuspend || actionInfo.Action == ModerateReinstate
+    if account && USERS[actionInfo.Admin].GetRole() <= target.GetRole() {
+        LOG[WARNING].Println(StatusText(StatusForbidden), actionInfo.Admin, actionInfo.Target)
+        serverEncoder.Encode(CommandResponse{false, StatusForbidden, nil})
+        return
+    }
+    applied := true
+    notice := ""
+    switch actionInfo.Action {
+        case ModerateDismiss:
+            applied = target.ResolveReports(actionInfo.Id) > 0
+        case ModerateTakedown:
+            applied = actionInfo.Id != 0 && target.TakeDown(actionInfo.Id, reason)
+            notice = "Moderators removed your chirp: " + reason
+        case ModerateRestore:
+            applied = actionInfo.Id != 0 && target.RestorePost(actionInfo.Id)
+            notice = "Moderators restored your chirp"
+        case ModerateSuspend:
+            applied = actionInfo.Id == 0
+            if applied {
+                target.Suspend(reason)
+            }
+            notice = "Moderators suspended your account: " + reason
+        case ModerateReinstate:
+            applied = actionInfo.Id == 0 && target.Reinstate()
+            notice = "Moderators reinstated your account"
+        case ModerateDenyAppeal:
+            applied = target.DenyAppeal(actionInfo.Id)
+            notice = "Moderators reviewed and denied your appeal"
+        case ModerateApprove:
+            applied = actionInfo.Id != 0 && target.Approve(actionInfo.Id)
+            notice = "Moderators approved your chirp"
+        default:
+            applied = false
+    }
+    if !applied {
+        LOG[INFO].Println(StatusText(StatusInvalidModeration), actionInfo.Target, actionInfo.Id, actionInfo.Action)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidModeration, nil})
+        return
+    }
+    LOG[INFO].Println("Moderator", actionInfo.Admin, "moderated", actionInfo.Target, actionInfo.Id, "with action", actionInfo.Action)
+    if notice != "" {
+        target.Notify(NotifyModeration, "", notice, now)  // the moderator is not named to the affected user
+    }
+    writeUser(target)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Appeal takes a username, a post id, 0 for a suspension, and a message and appeals the takedown or
+// suspension, each can be appealed once
+func appeal(serverEncoder *gob.Encoder, request CommandRequest) {
+    appealInfo, ok := request.Data.(struct{Username string; Id int; Message string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    message := NormalizeText(appealInfo.Message)
+    if !ValidReason(message) {
+        LOG[INFO].Println(StatusText(StatusInvalidReport), appealInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidReport, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[appealInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), appealInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if !user.Appeal(appealInfo.Id, message) {
+        LOG[INFO].Println(StatusText(StatusInvalidModeration), appealInfo.Username, appealInfo.Id)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidModeration, nil})
+        return
+    }
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Get standing takes a username and responds with the user's role, suspension, taken down posts and appeals
+func getStanding(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, user.GetStanding()})
+}
+
+// Get filters takes an admin's username and responds with the filter configuration in use
+func getFilters(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    if !hasRole(username, RoleAdmin) {
+        LOG[WARNING].Println(StatusText(StatusForbidden), username)
+        serverEncoder.Encode(CommandResponse{false, StatusForbidden, nil})
+        return
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, FILTERS.Config()})
+}
+
+/*
+    Set filters takes an admin's username and a JSON filter configuration, rebuilds the filter chain from it
+    and saves it to the configuration file, so new rules apply to the next chirp without a restart.
+    An invalid configuration is rejected with StatusInvalidFilters and the error text, the chain in use
+    is kept.
+*/
+func setFilters(serverEncoder *gob.Encoder, request CommandRequest) {
+    filterInfo, ok := request.Data.(struct{Admin, Rules string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    USERS_LOCK.RLock()
+    allowed := hasRole(filterInfo.Admin, RoleAdmin)
+    USERS_LOCK.RUnlock()
+    if !allowed {
+        LOG[WARNING].Println(StatusText(StatusForbidden), filterInfo.Admin)
+        serverEncoder.Encode(CommandResponse{false, StatusForbidden, nil})
+        return
+    }
+    if err := FILTERS.Load(filterInfo.Rules); err != nil {
+        LOG[INFO].Println(StatusText(StatusInvalidFilters), err)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidFilters, err.Error()})
+        return
+    }
+    if err := WriteFilterConfig(filterInfo.Rules); err != nil {
+        LOG[ERROR].Println("Unable to store filter config", err)
+    }
+    LOG[INFO].Println("Admin", filterInfo.Admin, "updated the filters")
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Get accounts takes an admin's username and a search query and responds with every account whose
+// username contains the query, with its role and suspension
+func getAccounts(serverEncoder *gob.Encoder, request CommandRequest) {
+    queryInfo, ok := request.Data.(struct{Admin, Query string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    if !hasRole(queryInfo.Admin, RoleAdmin) {
+        LOG[WARNING].Println(StatusText(StatusForbidden), queryInfo.Admin)
+        serverEncoder.Encode(CommandResponse{false, StatusForbidden, nil})
+        return
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, Accounts(USERS, queryInfo.Query)})
+}
+
+// Set role takes an admin's username, the target user and a role and assigns the role to the target
+// Admins cannot change their own role, so a cluster is never left without an admin by mistake
+func setRole(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    roleInfo, ok := request.Data.(struct{Admin, Target string; Role int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    if !ValidRole(roleInfo.Role) || roleInfo.Admin == roleInfo.Target {
+        LOG[INFO].Println(StatusText(StatusInvalidRole), roleInfo.Admin, roleInfo.Target, roleInfo.Role)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidRole, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    if !hasRole(roleInfo.Admin, RoleAdmin) {
+        LOG[WARNING].Println(StatusText(StatusForbidden), roleInfo.Admin)
+        serverEncoder.Encode(CommandResponse{false, StatusForbidden, nil})
+        return
+    }
+    target, ok := USERS[roleInfo.Target]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), roleInfo.Target)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    target.SetRole(roleInfo.Role)
+    target.Notify(NotifyModeration, "", "Your role is now " + RoleName(roleInfo.Role), now)
+    writeUser(target)
+    LOG[INFO].Println("Admin", roleInfo.Admin, "made", roleInfo.Target, RoleName(roleInfo.Role))
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+/*
+    Export data takes a username and starts writing an archive of the user's profile, posts, follows,
+    followers, lists, bookmarks and messages, responding before the archive is finished.
+    The user's data is copied when the export is asked for and the archive is written in the background,
+    replacing any earlier archive. Every server writes its own copy, so the archive survives a new master.
+    Asking again while an archive is being written does nothing.
+*/
+func exportData(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    EXPORTS_LOCK.Lock()
+    defer EXPORTS_LOCK.Unlock()
+    if EXPORTS[username].State == ExportRunning {
+        serverEncoder.Encode(CommandResponse{true, StatusAccepted, EXPORTS[username]})
+        return
+    }
+    status := ExportStatus{State: ExportRunning, Time: now.Format(time.RFC1123)[0:len(time.RFC1123)-4]}
+    EXPORTS[username] = status
+    go writeExport(user.Archive(now), status)
+    LOG[INFO].Println("Started export for", username)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, status})
+}
+
+// Write export writes the archive and records whether it succeeded, the archive is removed if the
+// account was deleted in the meantime
+func writeExport(archive Archive, status ExportStatus) {
+    username := archive.Profile.Username
+    size, err := WriteArchive(archive)
+    if err != nil {
+        LOG[ERROR].Println("Unable to write the export for", username, err)
+        status.State = ExportFailed
+    } else {
+        LOG[INFO].Println("Finished export for", username, size, "bytes")
+        status.State = ExportReady
+        status.Size = size
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    EXPORTS_LOCK.Lock()
+    defer EXPORTS_LOCK.Unlock()
+    if _, ok := USERS[username]; !ok {
+        RemoveArchive(username)
+        delete(EXPORTS, username)
+        return
+    }
+    EXPORTS[username] = status
+}
+
+// Get export takes a username and responds with the state of the user's data export
+func getExport(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    EXPORTS_LOCK.Lock()
+    defer EXPORTS_LOCK.Unlock()
+    status, ok := EXPORTS[username]
+    if !ok {
+        status, _ = FinishedExport(username)
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, status})
+}
+
+// Download export takes a username and responds with the user's finished archive as a zip file,
+// fails with StatusExportNotReady if there is none or it is still being written
+func downloadExport(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    EXPORTS_LOCK.Lock()
+    status, ok := EXPORTS[username]
+    EXPORTS_LOCK.Unlock()
+    if ok && status.State != ExportReady {
+        LOG[INFO].Println(StatusText(StatusExportNotReady), username)
+        serverEncoder.Encode(CommandResponse{false, StatusExportNotReady, nil})
+        return
+    }
+    data, err := ReadArchive(username)
+    if err != nil {
+        LOG[INFO].Println(StatusText(StatusExportNotReady), username, err)
+        serverEncoder.Encode(CommandResponse{false, StatusExportNotReady, nil})
+        return
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, data})
+}
+
+// Schedule chirp takes a username, post and publish time and stores the post until the scheduler publishes it
+// Publish times in the past are rejected with StatusInvalidSchedule, checked against the master's stamp so
+// every replica agrees
+func scheduleChirp(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    postInfo, ok := request.Data.(struct{Username, Post string; PublishAt time.Time})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    message, status := ValidatePost(postInfo.Post)
+    if status != StatusAccepted {
+        LOG[INFO].Println(StatusText(status), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, status, nil})
+        return
+    }
+    if postInfo.PublishAt.Before(now) {
+        LOG[WARNING].Println(StatusText(StatusInvalidSchedule), postInfo.Username, postInfo.PublishAt)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidSchedule, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[postInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if user.IsSuspended() {
+        LOG[INFO].Println(StatusText(StatusSuspended), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusSuspended, nil})
+        return
+    }
+    user.SchedulePost(message, postInfo.PublishAt)
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Get scheduled takes a username and responds with the user's scheduled posts, soonest first
+func getScheduled(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, user.GetScheduled()})
+}
+
+// Publish scheduled takes a username, scheduled post id and stamp and publishes the scheduled post as a chirp
+// made at the stamp, the stamp is chosen by the master's scheduler so every replica stores the same post
+// Responds with StatusPostNotFound if the post was already published or cancelled
+func publishScheduled(serverEncoder *gob.Encoder, request CommandRequest) {
+    postInfo, ok := request.Data.(struct{Username string; Id int; Stamp time.Time})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[postInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    scheduled, ok := user.TakeScheduled(postInfo.Id)
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusPostNotFound), postInfo.Username, postInfo.Id)
+        serverEncoder.Encode(CommandResponse{false, StatusPostNotFound, nil})
+        return
+    }
+    result := FILTERS.Run(Draft{user, scheduled.Message, false, postInfo.Stamp})
+    if result.Verdict == FilterReject {
+        writeUser(user)
+        LOG[INFO].Println(StatusText(StatusFiltered), postInfo.Username, "scheduled post", scheduled.Id, result.Reason)
+        serverEncoder.Encode(CommandResponse{false, StatusFiltered, nil})
+        return
+    }
+    draft := Post{Message: scheduled.Message, Stamp: postInfo.Stamp}
+    result.Apply(&draft)
+    post, mentioned := user.WritePost(draft, USERS)
+    INDEX.Add(post)
+    writeUser(user)
+    for _, name := range mentioned {
+        writeUser(USERS[name])
+    }
+    LOG[INFO].Println("Published scheduled post", scheduled.Id, "of", postInfo.Username)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Cancel scheduled takes a username and scheduled post id and removes the post before it is published
+// Responds with StatusPostNotFound if the post was already published or cancelled
+func cancelScheduled(serverEncoder *gob.Encoder, request CommandRequest) {
+    postInfo, ok := request.Data.(struct{Username string; Id int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[postInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if _, ok := user.TakeScheduled(postInfo.Id); !ok {
+        LOG[WARNING].Println(StatusText(StatusPostNotFound), postInfo.Username, postInfo.Id)
+        serverEncoder.Encode(CommandResponse{false, StatusPostNotFound, nil})
+        return
+    }
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Upload blob takes a username, file name and file contents and stores them in the blob store
+// It responds with the Attachment to include in a chirp, or StatusInvalidAttachment if the file is too large
+// or not a supported type
+func uploadBlob(serverEncoder *gob.Encoder, request CommandRequest) {
+    upload, ok := request.Data.(struct{Username, Name string; Data []byte})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    if _, ok := USERS[upload.Username]; !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), upload.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    attachment, err := CreateAttachment(upload.Name, upload.Data)
+    if err == ErrBlobTooLarge || err == ErrBlobType || err == ErrImageSize {
+        LOG[INFO].Println(StatusText(StatusInvalidAttachment), upload.Username, err)
+        serverEncoder.Encode(CommandResponse{false, StatusInvalidAttachment, nil})
+        return
+    }
+    if err != nil {
+        LOG[ERROR].Println("Unable to store blob", err)
+        serverEncoder.Encode(CommandResponse{false, StatusInternalError, nil})
+        return
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, attachment})
+}
+
+/*
+    Get blob takes a viewer and the hash of a blob and responds with its contents.
+    The blob is only served if it is attached to a post the viewer can see, otherwise it responds with
+    StatusBlobNotFound so a hidden post's attachments cannot be fetched or even confirmed to exist.
+*/
+func getBlob(serverEncoder *gob.Encoder, request CommandRequest) {
+    blobInfo, ok := request.Data.(struct{Viewer, Hash string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    hash := blobInfo.Hash
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    viewer, exists := USERS[blobInfo.Viewer]
+    if !exists {
+        LOG[INFO].Println(StatusText(StatusUserNotFound), blobInfo.Viewer)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if !canSeeBlob(viewer, hash) {
+        LOG[WARNING].Println(StatusText(StatusBlobNotFound), hash)
+        serverEncoder.Encode(CommandResponse{false, StatusBlobNotFound, nil})
+        return
+    }
+    data, err := ReadBlob(hash)
+    if err != nil {
+        LOG[WARNING].Println(StatusText(StatusBlobNotFound), hash)
+        serverEncoder.Encode(CommandResponse{false, StatusBlobNotFound, nil})
+        return
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, data})
+}
+
+// Can see blob checks if the blob is attached to any post the viewer can see
+// The caller must hold USERS_LOCK
+func canSeeBlob(viewer *UserInfo, hash string) bool {
+    for _, owner := range USERS {
+        for _, post := range owner.PostsWithBlob(hash) {
+            if owner.CanSeePost(viewer, post) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// Delete chirp takes a username and post id and removes the post from the user and the search index
+// It writes the change to a file and responds with StatusPostNotFound if the user has no such post
+func deleteChirp(serverEncoder *gob.Encoder, request CommandRequest) {
+    postInfo, ok := request.Data.(struct{Username string; Id int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[postInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if !user.DeletePost(postInfo.Id) {
+        LOG[WARNING].Println(StatusText(StatusPostNotFound), postInfo.Username, postInfo.Id)
+        serverEncoder.Encode(CommandResponse{false, StatusPostNotFound, nil})
+        return
+    }
+    INDEX.Remove(PostRef{postInfo.Username, postInfo.Id})
+    writeUser(user)
+    removeBookmarks(func(ref PostRef) bool {
+        return ref == PostRef{postInfo.Username, postInfo.Id}
+    })
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Expire chirps takes a username and the ids of posts chosen by the master's sweeper and removes them
+// from the user and the search index, ids of posts that no longer exist are ignored
+func expireChirps(serverEncoder *gob.Encoder, request CommandRequest) {
+    postInfo, ok := request.Data.(struct{Username string; Ids []int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[postInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), postInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    removed := user.DeletePosts(postInfo.Ids)
+    for _, id := range removed {
+        INDEX.Remove(PostRef{postInfo.Username, id})
+    }
+    if len(removed) > 0 {
+        writeUser(user)
+        LOG[INFO].Println("Expired", len(removed), "posts of", postInfo.Username)
+        expired := make(map[PostRef]bool)
+        for _, id := range removed {
+            expired[PostRef{postInfo.Username, id}] = true
+        }
+        removeBookmarks(func(ref PostRef) bool {
+            return expired[ref]
+        })
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Remove bookmarks removes the bookmarks the remove function returns true for from every user
+// and writes the users that changed, the caller must hold USERS_LOCK
+func removeBookmarks(remove func(PostRef) bool) {
+    for _, user := range USERS {
+        if user.RemoveBookmarksWhere(remove) {
+            writeUser(user)
+        }
+    }
+}
+
+// Bookmark takes a username, collection and the poster and id of a post and adds the post to or removes it from
+// the user's collection depending on the command code, an empty collection means DEFAULT_COLLECTION
+// Only posts the user can see can be bookmarked
+func bookmark(serverEncoder *gob.Encoder, request CommandRequest) {
+    bookmarkInfo, ok := request.Data.(struct{Username, Collection, Poster string; Id int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    if bookmarkInfo.Collection == "" {
+        bookmarkInfo.Collection = DEFAULT_COLLECTION
+    }
+    ref := PostRef{bookmarkInfo.Poster, bookmarkInfo.Id}
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[bookmarkInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), bookmarkInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+
+    if request.CommandCode == CommandRemoveBookmark {
+        if !user.RemoveBookmark(bookmarkInfo.Collection, ref) {
+            LOG[WARNING].Println(StatusText(StatusPostNotFound), bookmarkInfo.Username, ref)
+            serverEncoder.Encode(CommandResponse{false, StatusPostNotFound, nil})
+            return
+        }
+    } else {
+        poster, ok := USERS[ref.Poster]
+        if !ok {
+            LOG[WARNING].Println(StatusText(StatusPostNotFound), bookmarkInfo.Username, ref)
+            serverEncoder.Encode(CommandResponse{false, StatusPostNotFound, nil})
+            return
+        }
+        if post, ok := poster.GetPost(ref.Id); !ok || !poster.CanSeePost(user, post) {
+            LOG[WARNING].Println(StatusText(StatusPostNotFound), bookmarkInfo.Username, ref)
+            serverEncoder.Encode(CommandResponse{false, StatusPostNotFound, nil})
+            return
+        }
+        if !ValidCollectionName(bookmarkInfo.Collection) || !user.Bookmark(bookmarkInfo.Collection, ref) {
+            LOG[INFO].Println(StatusText(StatusInvalidBookmark), bookmarkInfo.Username, bookmarkInfo.Collection)
+            serverEncoder.Encode(CommandResponse{false, StatusInvalidBookmark, nil})
+            return
+        }
+    }
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Get bookmarks takes a username and collection and responds with the user's collections and the posts
+// in the collection, an empty collection means DEFAULT_COLLECTION
+func getBookmarks(serverEncoder *gob.Encoder, request CommandRequest) {
+    bookmarkInfo, ok := request.Data.(struct{Username, Collection string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    if bookmarkInfo.Collection == "" {
+        bookmarkInfo.Collection = DEFAULT_COLLECTION
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[bookmarkInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), bookmarkInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, user.GetBookmarks(bookmarkInfo.Collection, USERS)})
+}
+
+// Pin takes a username and the id of one of their posts and features it on their profile
+// An id of 0 unpins the current post, responds with StatusPostNotFound if the user has no such post
+func pin(serverEncoder *gob.Encoder, request CommandRequest) {
+    pinInfo, ok := request.Data.(struct{Username string; Id int})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[pinInfo.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), pinInfo.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if !user.Pin(pinInfo.Id) {
+        LOG[WARNING].Println(StatusText(StatusPostNotFound), pinInfo.Username, pinInfo.Id)
+        serverEncoder.Encode(CommandResponse{false, StatusPostNotFound, nil})
+        return
+    }
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Search chirps takes the searcher's username and the search text and responds with the matching
+// posts, newest first, leaving out posts of private accounts the searcher does not follow
+// and of users blocked by or blocking the searcher
+// Hidden posts are left out before the results are cut to MAX_SEARCH_RESULTS, so they never crowd out visible ones
+// The text may contain "quoted phrases" and a from:username author filter
+func searchChirps(serverEncoder *gob.Encoder, request CommandRequest) {
+    searchInfo, ok := request.Data.(struct{Searcher, Query string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    searcher, ok := USERS[searchInfo.Searcher]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), searchInfo.Searcher)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    LOG[INFO].Println("User", searchInfo.Searcher, "search chirps", searchInfo.Query)
+    result := []Post{}
+    now := time.Now()
+    for _, ref := range INDEX.Search(ParseChirpQuery(searchInfo.Query)) {
+        author, ok := USERS[ref.Poster]
+        if !ok {
+            continue
+        }
+        if post, ok := author.GetPost(ref.Id); ok && !post.Expired(now) && author.CanSeePost(searcher, post) {
+            result = append(result, post)
+            if len(result) == MAX_SEARCH_RESULTS {
+                break
+            }
+        }
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, result})
+}
+
+// Get chirps takes in a CommandRequest which contains a string that represents the user that
+// the frontend is trying to get the chirps of
+// The corresponding call to getChirps is called and are encoded back to the front end
+func getChrips(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, user.GetAllChirps(USERS)})
+}
+
+// Get notifications takes a username and responds with the user's notification inbox, newest first
+func getNotifications(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, user.GetNotifications()})
+}
+
+// Read notifications takes a username and marks all of the user's notifications as read
+// The change is written to the user's file
+func readNotifications(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    user.ReadNotifications()
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
+}
+
+// Send message takes a sender, a list of recipients and a message and adds the message to the
+// conversation between them in every member's copy
+// The message is normalized and rejected with StatusEmptyPost or StatusMessageTooLong by ValidateMessage
+// It fails if the sender is suspended, a recipient does not exist, is blocked, only accepts messages
+// from users they follow, the group is too big or the filters reject or hold the message
+func sendMessage(serverEncoder *gob.Encoder, request CommandRequest, now time.Time) {
+    msgInfo, ok := request.Data.(struct{Sender string; Recipients []string; Message string})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+    message, status := ValidateMessage(msgInfo.Message)
+    if status != StatusAccepted {
+        LOG[INFO].Println(StatusText(status), msgInfo.Sender)
+        serverEncoder.Encode(CommandResponse{false, status, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    sender, ok := USERS[msgInfo.Sender]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), msgInfo.Sender)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if sender.IsSuspended() {
+        LOG[INFO].Println(StatusText(StatusSuspended), msgInfo.Sender)
+        serverEncoder.Encode(CommandResponse{false, StatusSuspended, nil})
+        return
+    }
+    members := []string{sender.Username}
+    seen := map[string]bool{sender.Username: true}
+    for _, name := range msgInfo.Recipients {
+        recipient, ok := USERS[name]
+        if !ok || recipient.IsDeleted() {
+            LOG[WARNING].Println(StatusText(StatusUserNotFound), name)
+            serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+            return
+        }
+        if seen[name] {
+            continue
+        }
+        if EitherBlocked(sender, recipient) {
+            LOG[INFO].Println("User", name, "and", sender.Username, "are blocked")
+            serverEncoder.Encode(CommandResponse{false, StatusBlocked, nil})
+            return
+        }
+        if !recipient.AcceptsMessagesFrom(sender.Username) {
+            LOG[INFO].Println("User", name, "rejected message from", sender.Username)
+            serverEncoder.Encode(CommandResponse{false, StatusMessageRejected, nil})
+            return
+        }
+        seen[name] = true
+        members = append(members, name)
+    }
+    if len(members) < 2 {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), "no recipients")
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    if len(members) > MAX_CONVERSATION_MEMBERS {
+        LOG[INFO].Println(StatusText(StatusTooManyRecipients), len(members))
+        serverEncoder.Encode(CommandResponse{false, StatusTooManyRecipients, nil})
+        return
+    }
+
+    result := FILTERS.Run(Draft{sender, message, true, now})
+    if result.Verdict >= FilterHold {  // messages cannot wait for review, holding one rejects it
+        LOG[INFO].Println(StatusText(StatusFiltered), msgInfo.Sender, result.Reason)
+        serverEncoder.Encode(CommandResponse{false, StatusFiltered, nil})
+        return
+    }
+    for _, name := range members {
+        USERS[name].AddMessage(members, sender.Username, message, result.Tags, now)
+        writeUser(USERS[name])
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, ConversationKey(members)})
+}
+
+// Get conversations takes a username and responds with the user's inbox holding all of their conversations,
+// most recent first, and their message privacy setting
+func getConversations(serverEncoder *gob.Encoder, request CommandRequest) {
+    username, ok := request.Data.(string)
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, user.GetInbox()})
+}
+
+// Set message privacy takes a username and whether the user only accepts messages from users they follow
+// The change is written to the user's file
+func setMessagePrivacy(serverEncoder *gob.Encoder, request CommandRequest) {
+    setting, ok := request.Data.(struct{Username string; FollowingOnly bool})
+    if !ok {
+        LOG[ERROR].Println(StatusText(StatusDecodeError))
+        serverEncoder.Encode(CommandResponse{false, StatusDecodeError, nil})
+        return
+    }
+
+    USERS_LOCK.RLock()
+    defer USERS_LOCK.RUnlock()
+    user, ok := USERS[setting.Username]
+    if !ok {
+        LOG[WARNING].Println(StatusText(StatusUserNotFound), setting.Username)
+        serverEncoder.Encode(CommandResponse{false, StatusUserNotFound, nil})
+        return
+    }
+    user.SetMessagePrivacy(setting.FollowingOnly)
+    writeUser(user)
+
+    serverEncoder.Encode(CommandResponse{true, StatusAccepted, nil})
 }
 
 // WriteUser takes in a user info pointer and writes the user info to a file using gob
@@ -477,7 +2771,7 @@ func getChrips(serverEncoder *gob.Encoder, request CommandRequest) {
 func writeUser(user *UserInfo) {
     user.Lock()
     defer user.Unlock()
-    file, err := os.Create("../../data/" + user.Username)
+    file, err := os.Create(UserFile(user.Username))
     if err != nil {
         LOG[ERROR].Println("Unable to create file ", err)
         return
@@ -6,10 +6,14 @@ import(
     "encoding/gob"
     "encoding/hex"
     "html/template"
+    "io/ioutil"
     "log"
     "net"
     "net/http"
+    "net/url"
     "os"
+    "strconv"
+    "strings"
     "time"
 )
 
@@ -30,13 +34,93 @@ func main() {
     http.HandleFunc("/home", home)         // function for home page (main page for logged in users)
     http.HandleFunc("/error", errorPage)   // function for error page
     http.HandleFunc("/search-result", searchResult)    // function for search submission
-    http.HandleFunc("/delete-account", deleteAccount)  // function for account deletion submission
+    http.HandleFunc("/delete-account", deleteAccount)  // function for account deletion confirmation and submission
+    http.HandleFunc("/restore-account", restoreAccount)  // function for restoring a deleted account before it is purged
+    http.HandleFunc("/rename", rename)                 // function for changing the user's username
+    http.HandleFunc("/notifications", notifications)   // function for notification inbox page
+    http.HandleFunc("/messages", messages)             // function for direct message inbox and sending messages
+    http.HandleFunc("/conversation", conversation)     // function for a single conversation page
+    http.HandleFunc("/message-settings", messageSettings)  // function for message privacy submission
+    http.HandleFunc("/search-chirps", searchChirps)    // function for chirp search results page
+    http.HandleFunc("/delete-chirp", deleteChirp)      // function for chirp deletion submission
+    http.HandleFunc("/followers", followers)           // function for the list of a user's followers
+    http.HandleFunc("/following", following)           // function for the list of users a user follows
+    http.HandleFunc("/u/", profile)                    // function for public user profile pages
+    http.HandleFunc("/edit-profile", editProfile)      // function for editing the user's own profile
+    http.HandleFunc("/privacy", privacy)               // function for account privacy submission
+    http.HandleFunc("/retention", retention)           // function for post retention submission
+    http.HandleFunc("/media/", media)                  // function for serving chirp attachments
+    http.HandleFunc("/lists", lists)                   // function for a user's lists and creating or deleting lists
+    http.HandleFunc("/list", list)                     // function for a list's timeline and editing its members
+    http.HandleFunc("/bookmarks", bookmarks)           // function for bookmark collections and adding, moving or removing bookmarks
+    http.HandleFunc("/pin", pin)                       // function for pinning a chirp to the user's profile
+    http.HandleFunc("/poll", poll)                     // function for posting a chirp with a poll
+    http.HandleFunc("/vote", vote)                     // function for voting in a poll
+    http.HandleFunc("/follow-requests", followRequests)  // function for approving or denying follow requests
+    http.HandleFunc("/block", block)                   // function for block, unblock, mute and unmute submission
+    http.HandleFunc("/scheduled", scheduled)           // function for scheduling chirps and the list of scheduled chirps
+    http.HandleFunc("/report", report)                 // function for reporting a chirp or account
+    http.HandleFunc("/moderation", moderation)         // function for the admin moderation queue and moderation actions
+    http.HandleFunc("/standing", standing)             // function for the user's suspension, removed chirps and appeals
+    http.HandleFunc("/filters", filters)               // function for viewing and replacing the content filter rules
+    http.HandleFunc("/admin", admin)                   // function for managing accounts, their roles and suspensions
+    http.HandleFunc("/export", export)                 // function for asking for a data export and its progress
+    http.HandleFunc("/export/download", downloadExport)  // function for downloading the finished data export
 
     gob.Register([]Post{})
+    gob.Register([]string{})
+    gob.Register([]Notification{})
+    gob.Register([]UserResult{})
+    gob.Register([]Recommendation{})
+    gob.Register(Trends{})
+    gob.Register(UserPage{})
+    gob.Register(Profile{})
+    gob.Register(Inbox{})
     gob.Register(struct{Username, Password string}{})
     gob.Register(struct{Username1, Username2 string}{})
     gob.Register(struct{Searcher, Target string}{})
-    gob.Register(struct{Username, Post string}{})
+    gob.Register(struct{Username, Post string; Lifetime time.Duration; Attachments []Attachment; Visibility int}{})
+    gob.Register(struct{Sender string; Recipients []string; Message string}{})
+    gob.Register(struct{Username string; FollowingOnly bool}{})
+    gob.Register(struct{Username string; Id int}{})
+    gob.Register(struct{Searcher, Query string}{})
+    gob.Register(struct{Viewer, Target string; Page int}{})
+    gob.Register(struct{Username, DisplayName, Bio string}{})
+    gob.Register(struct{Username string; Private bool}{})
+    gob.Register(struct{Username, Requester string; Approve bool}{})
+    gob.Register([]ScheduledPost{})
+    gob.Register(struct{Username, Post string; PublishAt time.Time}{})
+    gob.Register(struct{Username string; Id int; Stamp time.Time}{})
+    gob.Register(struct{Username string; Retention time.Duration}{})
+    gob.Register(struct{Username string; Ids []int}{})
+    gob.Register(Attachment{})
+    gob.Register([]byte{})
+    gob.Register(struct{Viewer, Hash string}{})
+    gob.Register(struct{Username, Name string; Data []byte}{})
+    gob.Register([]List{})
+    gob.Register(ListTimeline{})
+    gob.Register(struct{Username, Name string}{})
+    gob.Register(struct{Username, Name string; Private bool}{})
+    gob.Register(struct{Username, Name, Member string}{})
+    gob.Register(struct{Viewer, Owner string}{})
+    gob.Register(struct{Viewer, Owner, Name string}{})
+    gob.Register(Bookmarks{})
+    gob.Register(struct{Username, Collection, Poster string; Id int}{})
+    gob.Register(struct{Username, Collection string}{})
+    gob.Register(struct{Username, Post string; Options []string; Duration time.Duration}{})
+    gob.Register(struct{Username, Poster string; Id, Option int}{})
+    gob.Register(struct{Username string; Id int; Counts []int}{})
+    gob.Register([]ModerationItem{})
+    gob.Register(Standing{})
+    gob.Register([]Account{})
+    gob.Register(ExportStatus{})
+    gob.Register(struct{Reporter, Target string; Id int; Reason string}{})
+    gob.Register(struct{Admin, Target string; Id, Action int; Reason string}{})
+    gob.Register(struct{Username string; Id int; Message string}{})
+    gob.Register(struct{Admin, Rules string}{})
+    gob.Register(struct{Admin, Query string}{})
+    gob.Register(struct{Admin, Target string; Role int}{})
+    gob.Register(struct{Username, NewName string}{})
 
     http.ListenAndServe(":8080", nil)
 }
@@ -61,8 +145,11 @@ func welcome(w http.ResponseWriter, r *http.Request) {
 
 /*
 Homepage function for users are the homepage. Checks cookie if they're logged in otherwise redirects to welcome
-Returns all the chirps from all users the person follows in a get and sends to html to display
-Post method sends a chirp to the system and redirects to itself to update the displayed chirps
+Returns all the chirps from all users the person follows in a get and sends to html to display along with
+the number of unread notifications, accounts suggested to follow and the topics trending over the window
+given by the window query parameter
+Post method uploads the files in the attachment form field and sends a chirp with them to the system, expiring after
+the number of hours in the lifetime form value if it is not zero, and redirects to itself to update the displayed chirps
  */
 func home(w http.ResponseWriter, r *http.Request) {
     LOG[INFO].Println("Home Page")
@@ -87,6 +174,42 @@ func home(w http.ResponseWriter, r *http.Request) {
             return
         }
 
+        notes := sendCommand(CommandRequest{CommandGetNotifications, cookie.Value})
+        if notes == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        unread := 0
+        if notes.Success {
+            for _, note := range notes.Data.([]Notification) {
+                if !note.Read {
+                    unread++
+                }
+            }
+        }
+
+        suggestions := sendCommand(CommandRequest{CommandRecommend, cookie.Value})
+        if suggestions == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        trending := sendCommand(CommandRequest{CommandGetTrending, r.FormValue("window")})
+        if trending == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        standing := sendCommand(CommandRequest{CommandGetStanding, cookie.Value})
+        if standing == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
         t, err := template.ParseFiles("../../web/homepage.html")
         if err != nil {
             LOG[ERROR].Println("HTML Template Error", err)
@@ -95,11 +218,19 @@ func home(w http.ResponseWriter, r *http.Request) {
             return
         }
         err = t.Execute(w, struct {
-            Username string
-            Posts    interface{}
+            Username        string
+            Posts           interface{}
+            Unread          int
+            Recommendations interface{}
+            Trends          interface{}
+            Standing        interface{}
         }{
             cookie.Value,
             response.Data,
+            unread,
+            suggestions.Data,
+            trending.Data,
+            standing.Data,
         })
         if err != nil {
             LOG[ERROR].Println("HTML Template Execution Error", err)
@@ -108,14 +239,36 @@ func home(w http.ResponseWriter, r *http.Request) {
         }
     } else if r.Method == http.MethodPost {
         LOG[INFO].Println("Executing Post")
-        r.ParseForm()
-        LOG[INFO].Println("Form Values: Post", r.PostFormValue("post"))
+        r.Body = http.MaxBytesReader(w, r.Body, MAX_ATTACHMENTS * MAX_BLOB_SIZE + 1 << 20)
+        r.ParseMultipartForm(1 << 20)
+        LOG[INFO].Println("Form Values: Post", r.PostFormValue("post"), "Lifetime", r.PostFormValue("lifetime"),
+            "Visibility", r.PostFormValue("visibility"))
+        hours, err := strconv.Atoi(r.PostFormValue("lifetime"))
+        if err != nil || hours < 0 {
+            hours = 0
+        }
+        visibility, err := strconv.Atoi(r.PostFormValue("visibility"))
+        if err != nil {
+            visibility = VisibilityPublic
+        }
+        attachments, status := uploadAttachments(r, cookie.Value)
+        if status != StatusAccepted {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
         response := sendCommand(CommandRequest{CommandChirp, struct{
-            Username string
-            Post     string
+            Username    string
+            Post        string
+            Lifetime    time.Duration
+            Attachments []Attachment
+            Visibility  int
         }{
             cookie.Value,
             r.PostFormValue("post"),
+            time.Duration(hours) * time.Hour,
+            attachments,
+            visibility,
         }})
         if response == nil {
             http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
@@ -132,6 +285,73 @@ func home(w http.ResponseWriter, r *http.Request) {
     }
 }
 
+// Upload attachments sends every file in the attachment field of a multipart form to the blob store
+// Returns the attachments to include in the chirp and StatusAccepted, or the status of the first failure
+func uploadAttachments(r *http.Request, username string) ([]Attachment, int) {
+    if r.MultipartForm == nil {
+        return nil, StatusAccepted
+    }
+    files := r.MultipartForm.File["attachment"]
+    if len(files) > MAX_ATTACHMENTS {
+        return nil, StatusInvalidAttachment
+    }
+    var attachments []Attachment
+    for _, header := range files {
+        if header.Size > MAX_BLOB_SIZE {
+            return nil, StatusInvalidAttachment
+        }
+        file, err := header.Open()
+        if err != nil {
+            LOG[ERROR].Println("Unable to open uploaded file", header.Filename, err)
+            return nil, StatusInternalError
+        }
+        data, err := ioutil.ReadAll(file)
+        file.Close()
+        if err != nil {
+            LOG[ERROR].Println("Unable to read uploaded file", header.Filename, err)
+            return nil, StatusInternalError
+        }
+        response := sendCommand(CommandRequest{CommandUploadBlob, struct{
+            Username string
+            Name     string
+            Data     []byte
+        }{
+            username,
+            header.Filename,
+            data,
+        }})
+        if response == nil {
+            return nil, StatusConnectionError
+        }
+        if !response.Success {
+            return nil, response.Status
+        }
+        attachments = append(attachments, response.Data.(Attachment))
+    }
+    return attachments, StatusAccepted
+}
+
+// Media serves the attachment blob named by the hash in the path, if it belongs to a post the user can see
+func media(w http.ResponseWriter, r *http.Request) {
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    hash := strings.TrimPrefix(r.URL.Path, "/media/")
+    response := sendCommand(CommandRequest{CommandGetBlob, struct{Viewer, Hash string}{cookie.Value, hash}})
+    if response == nil || !response.Success {
+        http.NotFound(w, r)
+        return
+    }
+    data := response.Data.([]byte)
+    // blobs never change, so the browser can keep them, but shared caches must not since access depends on the user
+    w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+    w.Header().Set("Content-Type", http.DetectContentType(data))
+    w.Header().Set("X-Content-Type-Options", "nosniff")
+    w.Write(data)
+}
+
 // Allows a user to signup
 // Post sends the signup credentials to the backend for verification and redirects accordingly if the signup was accepted
 func signup(w http.ResponseWriter, r *http.Request) {
@@ -181,6 +401,11 @@ func signup(w http.ResponseWriter, r *http.Request) {
             http.Redirect(w, r, "/error", http.StatusSeeOther)
             return
         }
+        if response.Status == StatusInvalidUsername || response.Status == StatusNameReserved {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
         if !response.Success {
             LOG[WARNING].Println(StatusText(response.Status))
             http.Redirect(w, r, "/signup", http.StatusSeeOther)
@@ -224,6 +449,11 @@ func login(w http.ResponseWriter, r *http.Request) {
             http.Redirect(w, r, "/error", http.StatusSeeOther)
             return
         }
+        if response.Status == StatusAccountDeleted {
+            LOG[INFO].Println("Offering Restore", r.PostFormValue("username"))
+            showRestore(w, r, r.PostFormValue("username"), response.Data)
+            return
+        }
         if !response.Success {
             LOG[WARNING].Println(StatusText(response.Status))
             http.Redirect(w, r, "/login", http.StatusSeeOther)
@@ -269,8 +499,9 @@ func errorPage(w http.ResponseWriter, r *http.Request) {
     }
 }
 
-// Searches for a user, provides user info if the user did not search for him/herself
-// provides a link to follow/unfollow based on current follow status
+// Searches for users, a get lists every username matching the search by prefix, substring or edit distance
+// with a link to follow/unfollow each based on current follow status
+// A post follows or unfollows the given user unless the user is the searcher
 func searchResult(w http.ResponseWriter, r *http.Request) {
     clearCache(w)
     exists, cookie := getCookie(r, LOGIN_COOKIE)
@@ -280,8 +511,51 @@ func searchResult(w http.ResponseWriter, r *http.Request) {
     }
     r.ParseForm()
     LOG[INFO].Println("Form Values: Username", r.FormValue("username"))
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Search Results Page")
+        response := sendCommand(CommandRequest{CommandSearchUsers, struct{
+            Searcher string
+            Query    string
+        }{
+            cookie.Value,
+            r.FormValue("username"),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[ERROR].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/search-result.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct{
+            Query   string
+            Results interface{}
+        }{
+            r.FormValue("username"),
+            response.Data,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+        return
+    }
+
     if cookie.Value == r.FormValue("username") {
-        LOG[INFO].Println("User Self Search")
+        LOG[INFO].Println("User Self Follow")
         http.Redirect(w, r, "/home", http.StatusSeeOther)
         return
     }
@@ -309,22 +583,7 @@ func searchResult(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    if r.Method == http.MethodGet {
-        LOG[INFO].Println("Search Results Page")
-        t, err := template.ParseFiles("../../web/search-result.html")
-        if err != nil {
-            LOG[ERROR].Println("HTML Template Error", err)
-            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
-            http.Redirect(w, r, "/error", http.StatusSeeOther)
-            return
-        }
-        err = t.Execute(w, struct{Username, Follow string}{r.FormValue("username"), response.Data.(string)})
-        if err != nil {
-            LOG[ERROR].Println("HTML Template Execution Error", err)
-            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
-            http.Redirect(w, r, "/error", http.StatusSeeOther)
-        }
-    } else if r.Method == http.MethodPost {
+    if r.Method == http.MethodPost {
         LOG[INFO].Println("Executing Follow/Unfollow")
         LOG[INFO].Println("Form Values: Username", r.PostFormValue("username"))
         r.ParseForm()
@@ -336,7 +595,7 @@ func searchResult(w http.ResponseWriter, r *http.Request) {
                 cookie.Value,
                 r.PostFormValue("username"),
             }})
-        } else if response.Data == "Unfollow" {
+        } else if response.Data == "Unfollow" || response.Data == "Requested" {
             response = sendCommand(CommandRequest{CommandUnfollow, struct {
                 Username1 string
                 Username2 string
@@ -361,65 +620,1770 @@ func searchResult(w http.ResponseWriter, r *http.Request) {
     }
 }
 
-// Delete account removes the user info cookie and sends a delete request to the backend
-func deleteAccount(w http.ResponseWriter, r *http.Request) {
+// Notifications displays the user's notification inbox in a get
+// Post marks every notification as read and redirects back to the inbox
+func notifications(w http.ResponseWriter, r *http.Request) {
     clearCache(w)
-    cookie, _ := r.Cookie(LOGIN_COOKIE)
-    sendCommand(CommandRequest{CommandDeleteAccount, cookie.Value})
-    cookie.MaxAge = -1
-    cookie.Expires = time.Now().Add(-1 * time.Hour)
-    http.SetCookie(w, cookie)
-    http.Redirect(w, r, "/welcome", http.StatusSeeOther)
-}
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
 
-// Gets a current cookie given the cookie name and returns if it exists
-func getCookie(r *http.Request, cookiename string) (bool, *http.Cookie) {
-    // Ignoring error value because it is likely that the cookie might not exist here
-    cookie, _ := r.Cookie(cookiename)
-    if cookie == nil {
-        return false, nil
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Notifications Page")
+        response := sendCommand(CommandRequest{CommandGetNotifications, cookie.Value})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/notifications.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username      string
+            Notifications interface{}
+        }{
+            cookie.Value,
+            response.Data,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        LOG[INFO].Println("Executing Mark Notifications Read")
+        response := sendCommand(CommandRequest{CommandReadNotifications, cookie.Value})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/notifications", http.StatusSeeOther)
     }
-    return true, cookie
 }
 
-// Takes a cookie name and value and creates a corresponding cookie
-// it returns the address of the cookie with a 24 hour expiration
-func genCookie(cookiename, value string) *http.Cookie {
-    return &http.Cookie{
-        Name:     cookiename,
-        Value:    value,
-        Expires:  time.Now().Add(24 * time.Hour),
+// Messages displays the user's direct message inbox in a get
+// Post sends a message to the comma separated recipients and redirects to the conversation
+func messages(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
     }
-}
 
-// Clear cache modifies the http header to guarantee no cache is stored
-func clearCache(w http.ResponseWriter) {
-    w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-    w.Header().Set("Pragma", "no-cache")
-    w.Header().Set("Expires", "0")
-}
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Messages Page")
+        response := sendCommand(CommandRequest{CommandGetConversations, cookie.Value})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
 
-// Send command takes in a formatted command request and sends it to the backend
-// it then reads the response and returns it
-func sendCommand(command CommandRequest) *CommandResponse {
-    conn, err := net.Dial("tcp", "127.0.0.1:5000")
-    if err != nil {
-        LOG[ERROR].Println(StatusText(StatusConnectionError), err, "retrying...")
-        // Sleep to allow some time for new master startup
-        time.Sleep(5 * time.Second)
-        conn, err = net.Dial("tcp", "127.0.0.1:5000")
-    }
-    if err != nil {
-        LOG[ERROR].Println(StatusText(StatusConnectionError), err)
-        return nil
+        t, err := template.ParseFiles("../../web/messages.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username string
+            Inbox    Inbox
+        }{
+            cookie.Value,
+            response.Data.(Inbox),
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        LOG[INFO].Println("Executing Send Message")
+        r.ParseForm()
+        LOG[INFO].Println("Form Values: To", r.PostFormValue("to"))
+        recipients := strings.FieldsFunc(r.PostFormValue("to"), func(c rune) bool {
+            return c == ',' || c == ' '
+        })
+        if len(recipients) == 0 || len(r.PostFormValue("message")) == 0 {
+            LOG[INFO].Println("bad param length on send message")
+            http.Redirect(w, r, "/messages", http.StatusSeeOther)
+            return
+        }
+        response := sendCommand(CommandRequest{CommandSendMessage, struct{
+            Sender     string
+            Recipients []string
+            Message    string
+        }{
+            cookie.Value,
+            recipients,
+            r.PostFormValue("message"),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        LOG[INFO].Println("Message Successfully Sent")
+        http.Redirect(w, r, "/conversation?key=" + url.QueryEscape(response.Data.(string)), http.StatusSeeOther)
     }
-    defer conn.Close()
+}
 
-    encoder := gob.NewEncoder(conn)
-    err = encoder.Encode(command)
-    if err != nil {
-        LOG[ERROR].Println(StatusText(StatusEncodeError), err)
-        return nil
+// Conversation displays every message of the conversation given by the key query parameter
+// Redirects to the inbox if the user is not a member of the conversation
+func conversation(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Conversation Page", r.FormValue("key"))
+    response := sendCommand(CommandRequest{CommandGetConversations, cookie.Value})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        LOG[WARNING].Println(StatusText(response.Status))
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+
+    for _, conv := range response.Data.(Inbox).Conversations {
+        if conv.Key != r.FormValue("key") {
+            continue
+        }
+        t, err := template.ParseFiles("../../web/conversation.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username     string
+            Others       string
+            Conversation Conversation
+        }{
+            cookie.Value,
+            strings.Join(conv.Others(cookie.Value), ","),
+            conv,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+        return
+    }
+    LOG[WARNING].Println("Conversation not found", r.FormValue("key"))
+    http.Redirect(w, r, "/messages", http.StatusSeeOther)
+}
+
+// Message settings sets whether the user only accepts direct messages from users they follow
+func messageSettings(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Redirect(w, r, "/messages", http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Executing Message Settings")
+    r.ParseForm()
+    response := sendCommand(CommandRequest{CommandSetMessagePrivacy, struct{
+        Username      string
+        FollowingOnly bool
+    }{
+        cookie.Value,
+        r.PostFormValue("following-only") == "on",
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    http.Redirect(w, r, "/messages", http.StatusSeeOther)
+}
+
+// Search chirps displays every chirp matching the q query parameter, newest first
+func searchChirps(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Form Values: Query", r.FormValue("q"))
+    response := sendCommand(CommandRequest{CommandSearchChirps, struct{
+        Searcher string
+        Query    string
+    }{
+        cookie.Value,
+        r.FormValue("q"),
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        LOG[WARNING].Println(StatusText(response.Status))
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+
+    t, err := template.ParseFiles("../../web/search-chirps.html")
+    if err != nil {
+        LOG[ERROR].Println("HTML Template Error", err)
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    err = t.Execute(w, struct {
+        Query string
+        Posts interface{}
+    }{
+        r.FormValue("q"),
+        response.Data,
+    })
+    if err != nil {
+        LOG[ERROR].Println("HTML Template Execution Error", err)
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+    }
+}
+
+// Followers lists the users following the user given by the username query parameter, one page at a time
+func followers(w http.ResponseWriter, r *http.Request) {
+    followList(w, r, CommandGetFollowers, "Followers of")
+}
+
+// Following lists the users followed by the user given by the username query parameter, one page at a time
+func following(w http.ResponseWriter, r *http.Request) {
+    followList(w, r, CommandGetFollowing, "Followed by")
+}
+
+// Follow list requests the given page of a follow list from the backend and displays it
+// The list defaults to the logged in user and the first page
+func followList(w http.ResponseWriter, r *http.Request, command int, title string) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    target := r.FormValue("username")
+    if target == "" {
+        target = cookie.Value
+    }
+    page, err := strconv.Atoi(r.FormValue("page"))
+    if err != nil {
+        page = 1
+    }
+    LOG[INFO].Println(title, target, "page", page)
+    response := sendCommand(CommandRequest{command, struct{
+        Viewer string
+        Target string
+        Page   int
+    }{
+        cookie.Value,
+        target,
+        page,
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        LOG[WARNING].Println(StatusText(response.Status))
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+
+    t, err := template.ParseFiles("../../web/follow-list.html")
+    if err != nil {
+        LOG[ERROR].Println("HTML Template Error", err)
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    list := response.Data.(UserPage)
+    err = t.Execute(w, struct {
+        Username string
+        Title    string
+        Path     string
+        List     UserPage
+        Prev     int
+        Next     int
+    }{
+        cookie.Value,
+        title,
+        r.URL.Path,
+        list,
+        list.Page - 1,
+        list.Page + 1,
+    })
+    if err != nil {
+        LOG[ERROR].Println("HTML Template Execution Error", err)
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+    }
+}
+
+// Profile displays the public profile of the user named in the path /u/{username}
+// along with the page of their posts given by the page query parameter
+func profile(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    target := strings.TrimPrefix(r.URL.Path, "/u/")
+    page, err := strconv.Atoi(r.FormValue("page"))
+    if err != nil {
+        page = 1
+    }
+    LOG[INFO].Println("Profile Page", target, "page", page)
+    response := sendCommand(CommandRequest{CommandGetProfile, struct{
+        Viewer string
+        Target string
+        Page   int
+    }{
+        cookie.Value,
+        target,
+        page,
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        LOG[WARNING].Println(StatusText(response.Status))
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+
+    t, err := template.ParseFiles("../../web/profile.html")
+    if err != nil {
+        LOG[ERROR].Println("HTML Template Error", err)
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    userProfile := response.Data.(Profile)
+    err = t.Execute(w, struct {
+        Username string
+        Profile  Profile
+        Prev     int
+        Next     int
+    }{
+        cookie.Value,
+        userProfile,
+        userProfile.Page - 1,
+        userProfile.Page + 1,
+    })
+    if err != nil {
+        LOG[ERROR].Println("HTML Template Execution Error", err)
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+    }
+}
+
+/*
+Lists displays the lists of the user given by the username query parameter, or the logged in user, in a get
+Post creates a list with the name form value, private if the private checkbox is set, or deletes the list when
+the action is Delete, then redirects back to the user's lists
+ */
+func lists(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    if r.Method == http.MethodGet {
+        owner := r.FormValue("username")
+        if owner == "" {
+            owner = cookie.Value
+        }
+        LOG[INFO].Println("Lists Page", owner)
+        response := sendCommand(CommandRequest{CommandGetLists, struct{
+            Viewer string
+            Owner  string
+        }{
+            cookie.Value,
+            owner,
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/lists.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username string
+            Owner    string
+            Lists    interface{}
+        }{
+            cookie.Value,
+            owner,
+            response.Data,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        r.ParseForm()
+        name := strings.TrimSpace(r.PostFormValue("name"))
+        var request CommandRequest
+        if r.PostFormValue("action") == "Delete" {
+            LOG[INFO].Println("Executing Delete List", name)
+            request = CommandRequest{CommandDeleteList, struct{
+                Username string
+                Name     string
+            }{
+                cookie.Value,
+                name,
+            }}
+        } else {
+            LOG[INFO].Println("Executing Create List", name)
+            request = CommandRequest{CommandCreateList, struct{
+                Username string
+                Name     string
+                Private  bool
+            }{
+                cookie.Value,
+                name,
+                r.PostFormValue("private") == "on",
+            }}
+        }
+        response := sendCommand(request)
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/lists", http.StatusSeeOther)
+    }
+}
+
+/*
+List displays the timeline and members of the list given by the username and name query parameters in a get
+Post adds the member form value to the logged in user's list given by the name form value, or removes them
+when the action is Remove, then redirects back to the list
+ */
+func list(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    if r.Method == http.MethodGet {
+        owner := r.FormValue("username")
+        if owner == "" {
+            owner = cookie.Value
+        }
+        LOG[INFO].Println("List Page", owner, r.FormValue("name"))
+        response := sendCommand(CommandRequest{CommandGetListTimeline, struct{
+            Viewer string
+            Owner  string
+            Name   string
+        }{
+            cookie.Value,
+            owner,
+            r.FormValue("name"),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/list.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username string
+            Timeline ListTimeline
+        }{
+            cookie.Value,
+            response.Data.(ListTimeline),
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        r.ParseForm()
+        LOG[INFO].Println("Executing Edit List", r.PostFormValue("name"), r.PostFormValue("action"), r.PostFormValue("member"))
+        command := CommandAddToList
+        if r.PostFormValue("action") == "Remove" {
+            command = CommandRemoveFromList
+        }
+        response := sendCommand(CommandRequest{command, struct{
+            Username string
+            Name     string
+            Member   string
+        }{
+            cookie.Value,
+            r.PostFormValue("name"),
+            strings.TrimSpace(r.PostFormValue("member")),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/list?name=" + url.QueryEscape(r.PostFormValue("name")), http.StatusSeeOther)
+    }
+}
+
+/*
+Bookmarks displays the user's bookmark collections and the chirps in the collection given by the collection
+query parameter in a get
+Post bookmarks the chirp given by the poster and id form values in the collection form value, removes it from
+the collection when the action is Remove, or moves it from the collection to the to form value when the action
+is Move, then redirects to the collection
+ */
+func bookmarks(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Bookmarks Page", r.FormValue("collection"))
+        response := sendCommand(CommandRequest{CommandGetBookmarks, struct{
+            Username   string
+            Collection string
+        }{
+            cookie.Value,
+            r.FormValue("collection"),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/bookmarks.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username  string
+            Bookmarks Bookmarks
+        }{
+            cookie.Value,
+            response.Data.(Bookmarks),
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        r.ParseForm()
+        LOG[INFO].Println("Executing Bookmark", r.PostFormValue("action"), r.PostFormValue("poster"), r.PostFormValue("id"))
+        id, err := strconv.Atoi(r.PostFormValue("id"))
+        if err != nil {
+            LOG[WARNING].Println("Bad post id", r.PostFormValue("id"))
+            http.Redirect(w, r, "/bookmarks", http.StatusSeeOther)
+            return
+        }
+        collection := strings.TrimSpace(r.PostFormValue("collection"))
+        // the payload must be the anonymous struct registered with gob
+        bookmarkRequest := func(command int, collection string) CommandRequest {
+            return CommandRequest{command, struct{
+                Username   string
+                Collection string
+                Poster     string
+                Id         int
+            }{
+                cookie.Value,
+                collection,
+                r.PostFormValue("poster"),
+                id,
+            }}
+        }
+        var requests []CommandRequest
+        switch r.PostFormValue("action") {
+            case "Remove":
+                requests = append(requests, bookmarkRequest(CommandRemoveBookmark, collection))
+            case "Move":
+                to := strings.TrimSpace(r.PostFormValue("to"))
+                requests = append(requests, bookmarkRequest(CommandBookmark, to))
+                if to != collection {
+                    requests = append(requests, bookmarkRequest(CommandRemoveBookmark, collection))
+                }
+                collection = to
+            default:
+                requests = append(requests, bookmarkRequest(CommandBookmark, collection))
+        }
+        for _, request := range requests {
+            response := sendCommand(request)
+            if response == nil {
+                http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+                http.Redirect(w, r, "/error", http.StatusSeeOther)
+                return
+            }
+            if !response.Success {
+                http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+                http.Redirect(w, r, "/error", http.StatusSeeOther)
+                return
+            }
+        }
+        http.Redirect(w, r, "/bookmarks?collection=" + url.QueryEscape(collection), http.StatusSeeOther)
+    }
+}
+
+// Pin features the chirp given by the id form value on the user's profile, an id of 0 unpins it,
+// and redirects to the user's profile
+func pin(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Redirect(w, r, "/u/" + cookie.Value, http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Executing Pin")
+    r.ParseForm()
+    id, err := strconv.Atoi(r.PostFormValue("id"))
+    if err != nil {
+        LOG[WARNING].Println("Bad post id", r.PostFormValue("id"))
+        http.Redirect(w, r, "/u/" + cookie.Value, http.StatusSeeOther)
+        return
+    }
+    response := sendCommand(CommandRequest{CommandPin, struct{
+        Username string
+        Id       int
+    }{
+        cookie.Value,
+        id,
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    http.Redirect(w, r, "/u/" + cookie.Value, http.StatusSeeOther)
+}
+
+// Poll sends a chirp with a poll made of the non empty option form values, closing after the number of
+// hours in the duration form value, and redirects to the home page
+func poll(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Redirect(w, r, "/home", http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Executing Poll")
+    r.ParseForm()
+    var options []string
+    for _, option := range r.PostForm["option"] {
+        if option = strings.TrimSpace(option); option != "" {
+            options = append(options, option)
+        }
+    }
+    hours, err := strconv.Atoi(r.PostFormValue("duration"))
+    if err != nil {
+        hours = 24
+    }
+    response := sendCommand(CommandRequest{CommandPollChirp, struct{
+        Username string
+        Post     string
+        Options  []string
+        Duration time.Duration
+    }{
+        cookie.Value,
+        r.PostFormValue("post"),
+        options,
+        time.Duration(hours) * time.Hour,
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    http.Redirect(w, r, "/home", http.StatusSeeOther)
+}
+
+// Vote sends a vote for the option form value in the poll of the chirp given by the poster and id form values
+// and redirects to the home page
+func vote(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Redirect(w, r, "/home", http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Executing Vote")
+    r.ParseForm()
+    id, err := strconv.Atoi(r.PostFormValue("id"))
+    option, err2 := strconv.Atoi(r.PostFormValue("option"))
+    if err != nil || err2 != nil {
+        LOG[WARNING].Println("Bad vote", r.PostFormValue("id"), r.PostFormValue("option"))
+        http.Redirect(w, r, "/home", http.StatusSeeOther)
+        return
+    }
+    response := sendCommand(CommandRequest{CommandVote, struct{
+        Username string
+        Poster   string
+        Id       int
+        Option   int
+    }{
+        cookie.Value,
+        r.PostFormValue("poster"),
+        id,
+        option,
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    http.Redirect(w, r, "/home", http.StatusSeeOther)
+}
+
+// Edit profile displays a form filled with the user's current display name and bio in a get
+// Post saves the new values and redirects to the user's profile
+func editProfile(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Edit Profile Page")
+        response := sendCommand(CommandRequest{CommandGetProfile, struct{
+            Viewer string
+            Target string
+            Page   int
+        }{
+            cookie.Value,
+            cookie.Value,
+            1,
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/edit-profile.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        profile := response.Data.(Profile)
+        type retentionChoice struct {
+            Label    string
+            Hours    int
+            Selected bool
+        }
+        var choices []retentionChoice
+        for _, choice := range []retentionChoice{{"Forever", 0, false}, {"1 day", 24, false}, {"1 week", 7 * 24, false},
+                                                 {"30 days", 30 * 24, false}, {"1 year", 365 * 24, false}} {
+            choice.Selected = time.Duration(choice.Hours) * time.Hour == profile.Retention
+            choices = append(choices, choice)
+        }
+        err = t.Execute(w, struct {
+            Username  string
+            Profile   Profile
+            Retention []retentionChoice
+        }{
+            cookie.Value,
+            profile,
+            choices,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        LOG[INFO].Println("Executing Edit Profile")
+        r.ParseForm()
+        response := sendCommand(CommandRequest{CommandEditProfile, struct{
+            Username    string
+            DisplayName string
+            Bio         string
+        }{
+            cookie.Value,
+            strings.TrimSpace(r.PostFormValue("display-name")),
+            strings.TrimSpace(r.PostFormValue("bio")),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/u/" + url.PathEscape(cookie.Value), http.StatusSeeOther)
+    }
+}
+
+// Rename changes the user's username, moves the login cookie to the new name and redirects to the profile
+func rename(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Redirect(w, r, "/edit-profile", http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Executing Rename")
+    r.ParseForm()
+    name := strings.TrimSpace(r.PostFormValue("username"))
+    response := sendCommand(CommandRequest{CommandRename, struct{
+        Username string
+        NewName  string
+    }{
+        cookie.Value,
+        name,
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    http.SetCookie(w, genCookie(LOGIN_COOKIE, name))
+    http.Redirect(w, r, "/u/" + url.PathEscape(name), http.StatusSeeOther)
+}
+
+// Privacy makes the user's account private or public and redirects to the edit profile page
+func privacy(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Redirect(w, r, "/edit-profile", http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Executing Privacy")
+    r.ParseForm()
+    response := sendCommand(CommandRequest{CommandSetPrivacy, struct{
+        Username string
+        Private  bool
+    }{
+        cookie.Value,
+        r.PostFormValue("private") == "on",
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    http.Redirect(w, r, "/edit-profile", http.StatusSeeOther)
+}
+
+// Retention sets how many hours the user's chirps are kept before being removed, zero keeps them forever,
+// and redirects back to the edit profile page
+func retention(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Redirect(w, r, "/edit-profile", http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Executing Retention")
+    r.ParseForm()
+    hours, err := strconv.Atoi(r.PostFormValue("hours"))
+    if err != nil {
+        LOG[WARNING].Println("Bad retention", r.PostFormValue("hours"))
+        http.Redirect(w, r, "/edit-profile", http.StatusSeeOther)
+        return
+    }
+    response := sendCommand(CommandRequest{CommandSetRetention, struct{
+        Username  string
+        Retention time.Duration
+    }{
+        cookie.Value,
+        time.Duration(hours) * time.Hour,
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    http.Redirect(w, r, "/edit-profile", http.StatusSeeOther)
+}
+
+// Follow requests lists the users waiting for approval to follow the user in a get
+// Post approves or denies the request of the given requester and redirects back to the list
+func followRequests(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Follow Requests Page")
+        response := sendCommand(CommandRequest{CommandGetFollowRequests, cookie.Value})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/follow-requests.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username string
+            Requests interface{}
+        }{
+            cookie.Value,
+            response.Data,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        LOG[INFO].Println("Executing Answer Follow Request")
+        r.ParseForm()
+        LOG[INFO].Println("Form Values: Requester", r.PostFormValue("requester"), "Answer", r.PostFormValue("answer"))
+        response := sendCommand(CommandRequest{CommandAnswerFollowRequest, struct{
+            Username  string
+            Requester string
+            Approve   bool
+        }{
+            cookie.Value,
+            r.PostFormValue("requester"),
+            r.PostFormValue("answer") == "Approve",
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/follow-requests", http.StatusSeeOther)
+    }
+}
+
+/*
+Allows a user to schedule chirps for later
+Get method lists the user's scheduled chirps, soonest first, with a form for scheduling a new one
+Post method cancels the scheduled chirp given by the id form value when the action is Cancel, otherwise it
+schedules the post form value for the local time given by the publish form value
+ */
+func scheduled(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Scheduled Chirps Page")
+        response := sendCommand(CommandRequest{CommandGetScheduled, cookie.Value})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/scheduled.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username  string
+            Scheduled interface{}
+        }{
+            cookie.Value,
+            response.Data,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        r.ParseForm()
+        var request CommandRequest
+        if r.PostFormValue("action") == "Cancel" {
+            LOG[INFO].Println("Executing Cancel Scheduled Chirp")
+            id, err := strconv.Atoi(r.PostFormValue("id"))
+            if err != nil {
+                LOG[WARNING].Println("Bad scheduled post id", r.PostFormValue("id"))
+                http.Redirect(w, r, "/scheduled", http.StatusSeeOther)
+                return
+            }
+            request = CommandRequest{CommandCancelScheduled, struct{
+                Username string
+                Id       int
+            }{
+                cookie.Value,
+                id,
+            }}
+        } else {
+            LOG[INFO].Println("Executing Schedule Chirp")
+            LOG[INFO].Println("Form Values: Post", r.PostFormValue("post"), "Publish", r.PostFormValue("publish"))
+            publishAt, err := time.ParseInLocation("2006-01-02T15:04", r.PostFormValue("publish"), time.Local)
+            if err != nil || !publishAt.After(time.Now()) {
+                http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(StatusInvalidSchedule)))
+                http.Redirect(w, r, "/error", http.StatusSeeOther)
+                return
+            }
+            request = CommandRequest{CommandScheduleChirp, struct{
+                Username  string
+                Post      string
+                PublishAt time.Time
+            }{
+                cookie.Value,
+                r.PostFormValue("post"),
+                publishAt,
+            }}
+        }
+        response := sendCommand(request)
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/scheduled", http.StatusSeeOther)
+    }
+}
+
+// Block sends the block, unblock, mute or unmute command given by the action form value for the
+// given user and redirects to that user's profile
+func block(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Redirect(w, r, "/home", http.StatusSeeOther)
+        return
+    }
+    r.ParseForm()
+    LOG[INFO].Println("Executing", r.PostFormValue("action"), r.PostFormValue("username"))
+    commands := map[string]int{
+        "Block":   CommandBlock,
+        "Unblock": CommandUnblock,
+        "Mute":    CommandMute,
+        "Unmute":  CommandUnmute,
+    }
+    command, ok := commands[r.PostFormValue("action")]
+    if !ok {
+        LOG[WARNING].Println("Unknown block action", r.PostFormValue("action"))
+        http.Redirect(w, r, "/home", http.StatusSeeOther)
+        return
+    }
+    response := sendCommand(CommandRequest{command, struct{
+        Username1 string
+        Username2 string
+    }{
+        cookie.Value,
+        r.PostFormValue("username"),
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    http.Redirect(w, r, "/u/" + url.PathEscape(r.PostFormValue("username")), http.StatusSeeOther)
+}
+
+// Report shows the form for reporting a chirp or account on GET and files the report on POST,
+// then redirects to the reported user's profile
+func report(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    id, err := strconv.Atoi(r.FormValue("id"))
+    if err != nil {
+        id = 0  // the account itself is reported
+    }
+
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Report Page", r.FormValue("target"), id)
+        t, err := template.ParseFiles("../../web/report.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username string
+            Target   string
+            Id       int
+        }{
+            cookie.Value,
+            r.FormValue("target"),
+            id,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        LOG[INFO].Println("Executing Report", r.PostFormValue("target"), id)
+        response := sendCommand(CommandRequest{CommandReport, struct{
+            Reporter string
+            Target   string
+            Id       int
+            Reason   string
+        }{
+            cookie.Value,
+            r.PostFormValue("target"),
+            id,
+            r.PostFormValue("reason"),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/u/" + url.PathEscape(r.PostFormValue("target")), http.StatusSeeOther)
+    }
+}
+
+// Moderation shows moderators the queue of reported and appealed chirps and accounts on GET and applies
+// the chosen action on POST, other users get the error page
+func moderation(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Moderation Queue Page")
+        response := sendCommand(CommandRequest{CommandGetModerationQueue, cookie.Value})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/moderation.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username string
+            Queue    interface{}
+            Actions  map[string]int
+        }{
+            cookie.Value,
+            response.Data,
+            map[string]int{
+                "Dismiss":   ModerateDismiss,
+                "Takedown":  ModerateTakedown,
+                "Restore":   ModerateRestore,
+                "Suspend":   ModerateSuspend,
+                "Reinstate": ModerateReinstate,
+                "Deny":      ModerateDenyAppeal,
+                "Approve":   ModerateApprove,
+            },
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        r.ParseForm()
+        LOG[INFO].Println("Executing Moderation", r.PostFormValue("target"), r.PostFormValue("id"), r.PostFormValue("action"))
+        id, err := strconv.Atoi(r.PostFormValue("id"))
+        action, err2 := strconv.Atoi(r.PostFormValue("action"))
+        if err != nil || err2 != nil {
+            LOG[WARNING].Println("Bad moderation form", r.PostFormValue("id"), r.PostFormValue("action"))
+            http.Redirect(w, r, "/moderation", http.StatusSeeOther)
+            return
+        }
+        response := sendCommand(CommandRequest{CommandModerate, struct{
+            Admin  string
+            Target string
+            Id     int
+            Action int
+            Reason string
+        }{
+            cookie.Value,
+            r.PostFormValue("target"),
+            id,
+            action,
+            r.PostFormValue("reason"),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/moderation", http.StatusSeeOther)
+    }
+}
+
+// Standing shows the user their suspension and removed chirps with the state of any appeal on GET
+// and submits an appeal on POST
+func standing(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Account Standing Page")
+        response := sendCommand(CommandRequest{CommandGetStanding, cookie.Value})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/standing.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username string
+            Standing interface{}
+        }{
+            cookie.Value,
+            response.Data,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        r.ParseForm()
+        LOG[INFO].Println("Executing Appeal", r.PostFormValue("id"))
+        id, err := strconv.Atoi(r.PostFormValue("id"))
+        if err != nil {
+            LOG[WARNING].Println("Bad appeal id", r.PostFormValue("id"))
+            http.Redirect(w, r, "/standing", http.StatusSeeOther)
+            return
+        }
+        response := sendCommand(CommandRequest{CommandAppeal, struct{
+            Username string
+            Id       int
+            Message  string
+        }{
+            cookie.Value,
+            id,
+            r.PostFormValue("message"),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/standing", http.StatusSeeOther)
+    }
+}
+
+// Filters shows admins the content filter rules on GET and replaces them on POST, an invalid
+// configuration is reported on the error page with the reason
+func filters(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Content Filters Page")
+        response := sendCommand(CommandRequest{CommandGetFilters, cookie.Value})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/filters.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username string
+            Rules    interface{}
+        }{
+            cookie.Value,
+            response.Data,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        r.ParseForm()
+        LOG[INFO].Println("Executing Set Filters")
+        response := sendCommand(CommandRequest{CommandSetFilters, struct{
+            Admin string
+            Rules string
+        }{
+            cookie.Value,
+            r.PostFormValue("rules"),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            message := StatusText(response.Status)
+            if reason, ok := response.Data.(string); ok {
+                message += ": " + reason
+            }
+            http.SetCookie(w, genCookie(ERROR_COOKIE, message))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/filters", http.StatusSeeOther)
+    }
+}
+
+// Delete chirp removes one of the user's own chirps and redirects home
+func deleteChirp(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Redirect(w, r, "/home", http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Executing Delete Chirp")
+    r.ParseForm()
+    id, err := strconv.Atoi(r.PostFormValue("id"))
+    if err != nil {
+        LOG[WARNING].Println("Bad post id", r.PostFormValue("id"))
+        http.Redirect(w, r, "/home", http.StatusSeeOther)
+        return
+    }
+    response := sendCommand(CommandRequest{CommandDeleteChirp, struct{
+        Username string
+        Id       int
+    }{
+        cookie.Value,
+        id,
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    http.Redirect(w, r, "/home", http.StatusSeeOther)
+}
+
+// Delete account asks for the password on GET, and on POST sends the delete request to the backend and
+// removes the user info cookie
+func deleteAccount(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Delete Account Page")
+        http.ServeFile(w, r, "../../web/delete-account.html")
+        return
+    }
+
+    LOG[INFO].Println("Executing Delete Account")
+    r.ParseForm()
+    passhash := sha512.Sum512([]byte(r.PostFormValue("password")))
+    response := sendCommand(CommandRequest{CommandDeleteAccount, struct{
+        Username string
+        Password string
+    }{
+        cookie.Value,
+        hex.EncodeToString(passhash[:]),
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        LOG[WARNING].Println(StatusText(response.Status))
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    cookie.MaxAge = -1
+    cookie.Expires = time.Now().Add(-1 * time.Hour)
+    http.SetCookie(w, cookie)
+    http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+}
+
+// Show restore renders the page offering to restore a deleted account, shown when its owner logs in
+func showRestore(w http.ResponseWriter, r *http.Request, username string, purgeAt interface{}) {
+    t, err := template.ParseFiles("../../web/restore-account.html")
+    if err != nil {
+        LOG[ERROR].Println("HTML Template Error", err)
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    err = t.Execute(w, struct {
+        Username string
+        PurgeAt  interface{}
+    }{
+        username,
+        purgeAt,
+    })
+    if err != nil {
+        LOG[ERROR].Println("HTML Template Execution Error", err)
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+    }
+}
+
+// Restore account restores a deleted account with the owner's password and logs them in
+func restoreAccount(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    if r.Method != http.MethodPost {
+        http.Redirect(w, r, "/login", http.StatusSeeOther)
+        return
+    }
+    LOG[INFO].Println("Executing Restore Account")
+    r.ParseForm()
+    passhash := sha512.Sum512([]byte(r.PostFormValue("password")))
+    response := sendCommand(CommandRequest{CommandRestoreAccount, struct{
+        Username string
+        Password string
+    }{
+        r.PostFormValue("username"),
+        hex.EncodeToString(passhash[:]),
+    }})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        LOG[WARNING].Println(StatusText(response.Status))
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+
+    LOG[INFO].Println("Successfully Restored", r.PostFormValue("username"))
+    http.SetCookie(w, genCookie(LOGIN_COOKIE, r.PostFormValue("username")))
+    http.Redirect(w, r, "/home", http.StatusSeeOther)
+}
+
+// Gets a current cookie given the cookie name and returns if it exists
+func getCookie(r *http.Request, cookiename string) (bool, *http.Cookie) {
+    // Ignoring error value because it is likely that the cookie might not exist here
+    cookie, _ := r.Cookie(cookiename)
+    if cookie == nil {
+        return false, nil
+    }
+    return true, cookie
+}
+
+// Takes a cookie name and value and creates a corresponding cookie
+// it returns the address of the cookie with a 24 hour expiration
+func genCookie(cookiename, value string) *http.Cookie {
+    return &http.Cookie{
+        Name:     cookiename,
+        Value:    value,
+        Expires:  time.Now().Add(24 * time.Hour),
+    }
+}
+
+// Clear cache modifies the http header to guarantee no cache is stored
+func clearCache(w http.ResponseWriter) {
+    w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+    w.Header().Set("Pragma", "no-cache")
+    w.Header().Set("Expires", "0")
+}
+
+// Send command takes in a formatted command request and sends it to the backend
+// it then reads the response and returns it
+func sendCommand(command CommandRequest) *CommandResponse {
+    conn, err := net.Dial("tcp", "127.0.0.1:5000")
+    if err != nil {
+        LOG[ERROR].Println(StatusText(StatusConnectionError), err, "retrying...")
+        // Sleep to allow some time for new master startup
+        time.Sleep(5 * time.Second)
+        conn, err = net.Dial("tcp", "127.0.0.1:5000")
+    }
+    if err != nil {
+        LOG[ERROR].Println(StatusText(StatusConnectionError), err)
+        return nil
+    }
+    defer conn.Close()
+
+    encoder := gob.NewEncoder(conn)
+    err = encoder.Encode(command)
+    if err != nil {
+        LOG[ERROR].Println(StatusText(StatusEncodeError), err)
+        return nil
     }
 
     var response CommandResponse
@@ -431,3 +2395,194 @@ func sendCommand(command CommandRequest) *CommandResponse {
     }
     return &response
 }
+
+// Admin shows admins every account matching the search with its role and suspension on GET, and on POST
+// assigns a role or suspends or reinstates the account, other users get the error page
+func admin(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    if r.Method == http.MethodGet {
+        LOG[INFO].Println("Admin Page", r.FormValue("query"))
+        response := sendCommand(CommandRequest{CommandGetAccounts, struct{
+            Admin string
+            Query string
+        }{
+            cookie.Value,
+            r.FormValue("query"),
+        }})
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            LOG[WARNING].Println(StatusText(response.Status))
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+
+        t, err := template.ParseFiles("../../web/admin.html")
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        err = t.Execute(w, struct {
+            Username  string
+            Query     string
+            Accounts  interface{}
+            Roles     []string
+            Suspend   int
+            Reinstate int
+        }{
+            cookie.Value,
+            r.FormValue("query"),
+            response.Data,
+            ROLE_NAMES,
+            ModerateSuspend,
+            ModerateReinstate,
+        })
+        if err != nil {
+            LOG[ERROR].Println("HTML Template Execution Error", err)
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+        }
+    } else if r.Method == http.MethodPost {
+        r.ParseForm()
+        LOG[INFO].Println("Executing Admin Action", r.PostFormValue("target"), r.PostFormValue("role"), r.PostFormValue("action"))
+        var response *CommandResponse
+        if role, err := strconv.Atoi(r.PostFormValue("role")); err == nil {
+            response = sendCommand(CommandRequest{CommandSetRole, struct{
+                Admin  string
+                Target string
+                Role   int
+            }{
+                cookie.Value,
+                r.PostFormValue("target"),
+                role,
+            }})
+        } else if action, err := strconv.Atoi(r.PostFormValue("action")); err == nil {
+            response = sendCommand(CommandRequest{CommandModerate, struct{
+                Admin  string
+                Target string
+                Id     int
+                Action int
+                Reason string
+            }{
+                cookie.Value,
+                r.PostFormValue("target"),
+                0,
+                action,
+                r.PostFormValue("reason"),
+            }})
+        } else {
+            LOG[WARNING].Println("Bad admin form", r.PostFormValue("role"), r.PostFormValue("action"))
+            http.Redirect(w, r, "/admin", http.StatusSeeOther)
+            return
+        }
+        if response == nil {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        if !response.Success {
+            http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+            http.Redirect(w, r, "/error", http.StatusSeeOther)
+            return
+        }
+        http.Redirect(w, r, "/admin?query=" + url.QueryEscape(r.PostFormValue("query")), http.StatusSeeOther)
+    }
+}
+
+// Export shows the state of the user's data export on GET and asks for a new archive on POST,
+// the page reloads itself while the archive is being written
+func export(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+
+    command := CommandGetExport
+    if r.Method == http.MethodPost {
+        LOG[INFO].Println("Executing Export")
+        command = CommandExport
+    }
+    response := sendCommand(CommandRequest{command, cookie.Value})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        LOG[WARNING].Println(StatusText(response.Status))
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if r.Method == http.MethodPost {
+        http.Redirect(w, r, "/export", http.StatusSeeOther)
+        return
+    }
+
+    LOG[INFO].Println("Export Page")
+    t, err := template.ParseFiles("../../web/export.html")
+    if err != nil {
+        LOG[ERROR].Println("HTML Template Error", err)
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    status, _ := response.Data.(ExportStatus)
+    err = t.Execute(w, struct {
+        Username string
+        Status   ExportStatus
+        Running  bool
+        Ready    bool
+        Failed   bool
+    }{
+        cookie.Value,
+        status,
+        status.State == ExportRunning,
+        status.State == ExportReady,
+        status.State == ExportFailed,
+    })
+    if err != nil {
+        LOG[ERROR].Println("HTML Template Execution Error", err)
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "HTML Template Execution Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+    }
+}
+
+// Download export sends the user's finished archive as a zip file download
+func downloadExport(w http.ResponseWriter, r *http.Request) {
+    clearCache(w)
+    exists, cookie := getCookie(r, LOGIN_COOKIE)
+    if !exists {
+        http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+        return
+    }
+    response := sendCommand(CommandRequest{CommandDownloadExport, cookie.Value})
+    if response == nil {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, "Send Command Error"))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    if !response.Success {
+        http.SetCookie(w, genCookie(ERROR_COOKIE, StatusText(response.Status)))
+        http.Redirect(w, r, "/error", http.StatusSeeOther)
+        return
+    }
+    data := response.Data.([]byte)
+    w.Header().Set("Content-Type", "application/zip")
+    w.Header().Set("Content-Disposition", "attachment; filename=\"chirp-" + cookie.Value + ".zip\"")
+    w.Write(data)
+}